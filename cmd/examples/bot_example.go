@@ -6,9 +6,9 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/rtexty/gokwork/pkg/kwork"
-	"github.com/rtexty/gokwork/pkg/kwork/types"
 )
 
 func main() {
@@ -23,23 +23,20 @@ func main() {
 	}
 	defer bot.Close()
 
+	// Загружаем шаблоны ответов: templates/<язык>/<имя>.tmpl
+	templates, err := kwork.LoadTemplates("cmd/examples/templates")
+	if err != nil {
+		log.Fatalf("Failed to load templates: %v", err)
+	}
+
 	// Регистрируем обработчик для первого сообщения от юзера
-	bot.MessageHandler("", true, "", func(ctx context.Context, msg *types.Message) error {
-		text := "Здравствуйте, рад что вы обратились именно ко мне, опишите ваше желание подробнее!"
-		return msg.AnswerSimulation(ctx, text)
-	})
+	bot.MessageHandler("", true, "", bot.TemplateReply(templates, "greeting"))
 
 	// Регистрируем обработчик для сообщений содержащих слово "бот"
-	bot.MessageHandler("", false, "бот", func(ctx context.Context, msg *types.Message) error {
-		text := "Вам нужен бот? Можете посмотреть на примеры уже сделанных:..."
-		return msg.AnswerSimulation(ctx, text)
-	})
+	bot.MessageHandler("", false, "бот", bot.TemplateReply(templates, "bot"))
 
 	// Регистрируем обработчик для точного совпадения текста "привет"
-	bot.MessageHandler("привет", false, "", func(ctx context.Context, msg *types.Message) error {
-		text := "И вам привет!"
-		return msg.AnswerSimulation(ctx, text)
-	})
+	bot.MessageHandler("привет", false, "", bot.TemplateReply(templates, "hello"))
 
 	// Создаем контекст с возможностью отмены
 	ctx, cancel := context.WithCancel(context.Background())
@@ -49,6 +46,9 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
+	// Перечитываем шаблоны при изменении файлов
+	go templates.Watch(ctx, 5*time.Second)
+
 	go func() {
 		<-sigChan
 		log.Println("Received interrupt signal, shutting down...")
@@ -0,0 +1,82 @@
+// kworkbot запускает бота Kwork по файлу правил.
+//
+// Логин и пароль берутся из переменных окружения KWORK_LOGIN и KWORK_PASSWORD,
+// последние цифры телефона — из KWORK_PHONE_LAST, прокси — из KWORK_PROXY.
+//
+//	kworkbot -rules rules.yaml
+//	kworkbot -rules rules.yaml -check
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/rtexty/gokwork/pkg/kwork"
+	"github.com/rtexty/gokwork/pkg/kwork/rules"
+)
+
+func main() {
+	rulesPath := flag.String("rules", "rules.yaml", "файл правил в формате YAML или JSON")
+	check := flag.Bool("check", false, "только проверить файл правил")
+	reload := flag.Duration("reload", 5*time.Second, "период проверки изменений шаблонов, 0 — не перечитывать")
+	flag.Parse()
+
+	cfg, err := rules.Load(*rulesPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	var templates *kwork.Templates
+	if dir := cfg.TemplatesDir(); dir != "" {
+		if templates, err = kwork.LoadTemplates(dir); err != nil {
+			log.Fatalf("Failed to load templates: %v", err)
+		}
+	}
+
+	if err := cfg.CheckTemplates(templates); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if *check {
+		log.Printf("%s: %d rules OK", *rulesPath, len(cfg.Rules))
+		return
+	}
+
+	bot, err := kwork.NewBot(kwork.Config{
+		Login:     os.Getenv("KWORK_LOGIN"),
+		Password:  os.Getenv("KWORK_PASSWORD"),
+		PhoneLast: os.Getenv("KWORK_PHONE_LAST"),
+		ProxyURL:  os.Getenv("KWORK_PROXY"),
+	})
+	if err != nil {
+		log.Fatalf("Failed to create bot: %v", err)
+	}
+	defer bot.Close()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	bot.Use(kwork.Recover(), kwork.Logging())
+	if err := cfg.Apply(ctx, bot, templates); err != nil {
+		log.Fatalf("Failed to apply rules: %v", err)
+	}
+
+	if templates != nil && *reload > 0 {
+		go templates.Watch(ctx, *reload)
+	}
+
+	log.Printf("Starting bot with %d rules from %s", len(cfg.Rules), *rulesPath)
+	if err := bot.Run(ctx); err != nil && err != context.Canceled {
+		log.Fatalf("Bot error: %v", err)
+	}
+
+	log.Println("Bot stopped gracefully")
+}
@@ -5,4 +5,5 @@ go 1.25.1
 require (
 	github.com/gorilla/websocket v1.5.3
 	golang.org/x/net v0.44.0
+	gopkg.in/yaml.v3 v3.0.1
 )
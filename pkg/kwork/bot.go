@@ -2,8 +2,10 @@ package kwork
 
 import (
 	"context"
+	stderrors "errors"
 	"log"
-	"strings"
+	"sync"
+	"time"
 
 	"github.com/rtexty/gokwork/pkg/kwork/errors"
 	"github.com/rtexty/gokwork/pkg/kwork/types"
@@ -12,7 +14,7 @@ import (
 // HandlerFunc функция-обработчик сообщения
 type HandlerFunc func(ctx context.Context, msg *types.Message) error
 
-// Handler представляет обработчик сообщения
+// Handler условия обработчика, регистрируемого через MessageHandler
 type Handler struct {
 	Func         HandlerFunc
 	Text         string
@@ -23,7 +25,19 @@ type Handler struct {
 // Bot представляет бота Kwork
 type Bot struct {
 	*Client
-	handlers []Handler
+	routes     []*Route
+	middleware []Middleware
+	dedup      *dedupCache
+	fsm        *FSM
+	contacts   ContactStore
+
+	handlerWorkers int
+	drainTimeout   time.Duration
+
+	// Профиль аккаунта для шаблонов ответов
+	meMu     sync.Mutex
+	me       *types.Actor
+	meLoaded time.Time
 }
 
 // NewBot создает нового бота
@@ -33,107 +47,189 @@ func NewBot(cfg Config) (*Bot, error) {
 		return nil, err
 	}
 
+	contacts := cfg.ContactStore
+	if contacts == nil {
+		contacts = NewMemoryContactStore()
+	}
+
 	return &Bot{
-		Client:   client,
-		handlers: make([]Handler, 0),
+		Client: client,
+		dedup:  newDedupCache(cfg.DedupTTL, cfg.DedupSize),
+		fsm:    NewFSM(cfg.StateStorage, cfg.StateTimeout),
+
+		contacts: contacts,
+
+		handlerWorkers: cfg.HandlerWorkers,
+		drainTimeout:   cfg.DrainTimeout,
 	}, nil
 }
 
-// MessageHandler регистрирует обработчик сообщений
+// Handle регистрирует обработчик сообщений, прошедших фильтр.
+// nil фильтр пропускает все сообщения. Сообщение получают все подходящие
+// обработчики в порядке приоритета, пока один из них не вернет
+// errors.ErrStopPropagation.
+func (b *Bot) Handle(filter Filter, handler HandlerFunc) *Route {
+	route := &Route{filter: filter, handler: handler}
+	b.routes = append(b.routes, route)
+	return route
+}
+
+// Use добавляет middleware, которые оборачивают вызов каждого обработчика бота.
+// Первая добавленная middleware выполняется первой.
+func (b *Bot) Use(middleware ...Middleware) {
+	b.middleware = append(b.middleware, middleware...)
+}
+
+// Group создает группу обработчиков со своими middleware
+func (b *Bot) Group(middleware ...Middleware) *Group {
+	return &Group{bot: b, middleware: middleware}
+}
+
+// FSM возвращает автомат многошаговых диалогов бота
+func (b *Bot) FSM() *FSM {
+	return b.fsm
+}
+
+// OnState регистрирует обработчик сообщений пользователей в состоянии state
+func (b *Bot) OnState(state string, handler HandlerFunc) *Route {
+	return b.Handle(InState(state), handler)
+}
+
+// MessageHandler регистрирует обработчик сообщений.
+// Сообщение обрабатывается, если выполнено хотя бы одно из условий:
+// первое сообщение в диалоге, точное совпадение текста или наличие слова.
+// Без условий обрабатываются все сообщения.
 func (b *Bot) MessageHandler(text string, onStart bool, textContains string, handler HandlerFunc) {
-	b.handlers = append(b.handlers, Handler{
+	h := Handler{
 		Func:         handler,
 		Text:         text,
 		OnStart:      onStart,
 		TextContains: textContains,
-	})
+	}
+	b.Handle(h.filter(), h.Func)
+}
+
+// filter собирает фильтр из условий обработчика
+func (h Handler) filter() Filter {
+	var filters []Filter
+	if h.OnStart {
+		filters = append(filters, FirstMessage())
+	}
+	if h.Text != "" {
+		filters = append(filters, Exact(h.Text))
+	}
+	if h.TextContains != "" {
+		filters = append(filters, ContainsWord(h.TextContains))
+	}
+
+	if len(filters) == 0 {
+		return nil
+	}
+	return Or(filters...)
 }
 
 // Run запускает бота
 func (b *Bot) Run(ctx context.Context) error {
-	if len(b.handlers) == 0 {
+	if len(b.routes) == 0 {
 		return errors.NewKworkBotError("no handlers registered")
 	}
 
 	log.Println("Bot is running!")
 
+	routes := b.buildRoutes()
+
 	messageChan := make(chan *types.Message, 100)
 
+	// Поддерживаем статус онлайн, пока бот работает
+	if b.keepOnlineInterval > 0 {
+		go func() {
+			if err := b.KeepOnline(ctx, b.keepOnlineInterval); err != nil && ctx.Err() == nil {
+				log.Printf("Keep online error: %v", err)
+			}
+		}()
+	}
+
 	// Запускаем слушатель сообщений в отдельной горутине
+	listenerErr := make(chan error, 1)
 	go func() {
-		if err := b.MessageListener(ctx, messageChan); err != nil {
-			log.Printf("Message listener error: %v", err)
-		}
+		listenerErr <- b.MessageListener(ctx, messageChan)
 	}()
 
-	// Обрабатываем входящие сообщения
+	// Обрабатываем входящие сообщения: разные отправители параллельно,
+	// сообщения одного отправителя — по порядку
+	pool := newHandlerPool(ctx, b.handlerWorkers, b.drainTimeout, func(ctx context.Context, msg *types.Message) {
+		b.dispatch(ctx, routes, msg)
+	})
+
 	for {
 		select {
 		case <-ctx.Done():
+			pool.drain()
 			return ctx.Err()
-		case msg := <-messageChan:
-			// Обрабатываем сообщение всеми подходящими хендлерами
-			for _, handler := range b.handlers {
-				if b.shouldHandleMessage(ctx, msg, &handler) {
-					log.Printf("Found handler for message: %s", msg.Text)
-					if err := handler.Func(ctx, msg); err != nil {
-						log.Printf("Handler error: %v", err)
-					}
+		case err := <-listenerErr:
+			// Слушатель завершается сам, когда исчерпаны попытки переподключения
+			// или закончилась запись кадров. Сообщения, которые он успел
+			// передать, обрабатываются до выхода.
+			for drained := false; !drained; {
+				select {
+				case msg := <-messageChan:
+					b.submit(pool, msg)
+				default:
+					drained = true
 				}
 			}
-		}
-	}
-}
-
-// shouldHandleMessage проверяет, должен ли хендлер обработать сообщение
-func (b *Bot) shouldHandleMessage(ctx context.Context, msg *types.Message, handler *Handler) bool {
-	// Если не установлены никакие условия, обрабатываем все сообщения
-	if !handler.OnStart && handler.Text == "" && handler.TextContains == "" {
-		return true
-	}
+			pool.wait()
 
-	// Проверяем условие "первое сообщение"
-	if handler.OnStart {
-		dialogs, err := b.GetAllDialogs(ctx)
-		if err != nil || len(dialogs) == 0 {
-			return false
-		}
-
-		fromUsername := dialogs[0].Username
-		dialog, err := b.GetDialogWithUser(ctx, fromUsername)
-		if err != nil {
-			return false
+			if err != nil {
+				log.Printf("Message listener error: %v", err)
+			}
+			return err
+		case msg := <-messageChan:
+			b.submit(pool, msg)
 		}
-
-		// Если в диалоге только одно сообщение, это первое сообщение
-		return len(dialog) == 1
 	}
+}
 
-	// Проверяем точное совпадение текста
-	if handler.Text != "" && strings.EqualFold(handler.Text, msg.Text) {
-		return true
+// submit передает сообщение на обработку, пропуская повторы
+func (b *Bot) submit(pool *handlerPool, msg *types.Message) {
+	// Одно сообщение может прийти несколькими событиями
+	if b.dedup.seen(msg) {
+		log.Printf("Skipping duplicate message %s", msg.Key())
+		return
 	}
+	pool.submit(msg)
+}
 
-	// Проверяем вхождение текста
-	if handler.TextContains != "" && b.dispatchTextContains(handler.TextContains, msg.Text) {
-		return true
+// dispatch передает сообщение всем подходящим обработчикам
+func (b *Bot) dispatch(ctx context.Context, routes []*Route, msg *types.Message) {
+	// Фиксируем состояние диалога до вызова обработчиков
+	if state, err := b.fsm.State(msg.FromID); err != nil {
+		log.Printf("Failed to load conversation state: %v", err)
+	} else {
+		ctx = withConversation(ctx, state)
 	}
 
-	return false
-}
+	ctx = withMessageScope(ctx)
+	ctx, fc := withFirstContact(ctx)
+	// Если ни один фильтр не проверял первое сообщение, просто запоминаем
+	// отправителя, чтобы следующие его сообщения не считались первыми
+	defer fc.once.Do(func() {
+		b.rememberContact(msg.FromID)
+	})
 
-// dispatchTextContains проверяет содержит ли сообщение указанный текст
-func (b *Bot) dispatchTextContains(text, messageText string) bool {
-	lowerText := strings.ToLower(text)
-	words := strings.Fields(strings.ToLower(messageText))
+	for _, route := range routes {
+		if !route.matches(ctx, b, msg) {
+			continue
+		}
 
-	for _, word := range words {
-		// Убираем знаки препинания
-		cleanWord := strings.Trim(word, "...!.?-")
-		if cleanWord == lowerText {
-			return true
+		log.Printf("Found handler for message: %s", msg.Text)
+		err := route.wrapped(ctx, msg)
+		if stderrors.Is(err, errors.ErrStopPropagation) {
+			return
+		}
+		// Ответ, задержанный Guard, уже залогирован
+		if err != nil && !stderrors.Is(err, errors.ErrReplySuppressed) {
+			log.Printf("Handler error: %v", err)
 		}
 	}
-
-	return false
 }
@@ -0,0 +1,102 @@
+package kwork
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/rtexty/gokwork/pkg/kwork/types"
+)
+
+// firstContact результат проверки первого сообщения, общий для всех
+// фильтров, через которые проходит одно сообщение
+type firstContact struct {
+	once  sync.Once
+	first bool
+}
+
+type firstContactKey struct{}
+
+// withFirstContact добавляет в контекст место для результата проверки первого сообщения
+func withFirstContact(ctx context.Context) (context.Context, *firstContact) {
+	fc := &firstContact{}
+	return context.WithValue(ctx, firstContactKey{}, fc), fc
+}
+
+// isFirstMessage проверяет, что сообщение — первое от этого отправителя.
+// Для одного сообщения проверка выполняется один раз, сколько бы фильтров
+// ее ни запросили.
+func (b *Bot) isFirstMessage(ctx context.Context, msg *types.Message) bool {
+	fc, ok := ctx.Value(firstContactKey{}).(*firstContact)
+	if !ok {
+		return b.detectFirstContact(ctx, msg)
+	}
+
+	fc.once.Do(func() {
+		fc.first = b.detectFirstContact(ctx, msg)
+	})
+	return fc.first
+}
+
+// detectFirstContact проверяет отправителя по хранилищу известных собеседников
+// и запоминает его. Отправитель, которого нет в хранилище, сверяется с историей
+// диалога: так при первом запуске с пустым хранилищем старые клиенты
+// не считаются новыми.
+func (b *Bot) detectFirstContact(ctx context.Context, msg *types.Message) bool {
+	known, err := b.contacts.Known(msg.FromID)
+	if err != nil {
+		log.Printf("Failed to check known contact %d: %v", msg.FromID, err)
+		return false
+	}
+	if known {
+		return false
+	}
+
+	first, err := b.hasNoEarlierMessages(ctx, msg)
+	if err != nil {
+		// Не запоминаем отправителя, чтобы проверить его при следующем сообщении
+		log.Printf("Failed to check dialog history with user %d: %v", msg.FromID, err)
+		return false
+	}
+
+	b.rememberContact(msg.FromID)
+	return first
+}
+
+// hasNoEarlierMessages проверяет, что в диалоге с отправителем нет сообщений
+// раньше msg. Более поздние сообщения не учитываются: клиент мог написать
+// несколько сообщений подряд до того, как бот обработал первое.
+func (b *Bot) hasNoEarlierMessages(ctx context.Context, msg *types.Message) (bool, error) {
+	user, err := b.GetUser(ctx, msg.FromID)
+	if err != nil {
+		return false, err
+	}
+
+	messages, err := b.GetDialogWithUser(ctx, user.Username)
+	if err != nil {
+		return false, err
+	}
+
+	// Время сообщения неизвестно: ранними считаем все сообщения, кроме него самого
+	if msg.Time == 0 {
+		return len(messages) <= 1, nil
+	}
+
+	for _, m := range messages {
+		if m.MessageID == msg.MessageID && msg.MessageID != 0 {
+			continue
+		}
+		if m.Time < msg.Time || (m.Time == msg.Time && msg.MessageID != 0 && m.MessageID < msg.MessageID) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// rememberContact запоминает отправителя как известного собеседника
+func (b *Bot) rememberContact(userID int) {
+	if err := b.contacts.Add(userID); err != nil {
+		log.Printf("Failed to remember contact %d: %v", userID, err)
+	}
+}
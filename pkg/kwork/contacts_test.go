@@ -0,0 +1,66 @@
+package kwork
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rtexty/gokwork/pkg/kwork/types"
+)
+
+func newContactsBot(t *testing.T, inboxes string) *Bot {
+	t.Helper()
+
+	bot, _ := newRouteBot(t, map[string]string{
+		"user":    `{"success":true,"response":{"id":"8","username":"buyer"}}`,
+		"inboxes": inboxes,
+	})
+	return bot
+}
+
+// firstMessages прогоняет сообщения через бота и возвращает ID тех,
+// что прошли фильтр FirstMessage
+func firstMessages(bot *Bot, messages ...*types.Message) []int {
+	var matched []int
+	bot.Handle(FirstMessage(), func(ctx context.Context, msg *types.Message) error {
+		matched = append(matched, msg.MessageID)
+		return nil
+	})
+
+	routes := bot.buildRoutes()
+	for _, msg := range messages {
+		bot.dispatch(context.Background(), routes, msg)
+	}
+	return matched
+}
+
+func TestFirstMessageNewSender(t *testing.T) {
+	bot := newContactsBot(t, `{"success":true,"response":[
+		{"message_id":11,"from_id":8,"to_id":1,"message":"Здравствуйте","time":200}
+	],"paging":{"page":1,"pages":1}}`)
+
+	matched := firstMessages(bot,
+		&types.Message{FromID: 8, MessageID: 11, Time: 200, Text: "Здравствуйте"},
+		&types.Message{FromID: 8, MessageID: 12, Time: 210, Text: "Вы тут?"},
+	)
+	if !equalIDs(matched, []int{11}) {
+		t.Errorf("FirstMessage matched %v, want only the first message [11]", matched)
+	}
+
+	known, err := bot.contacts.Known(8)
+	if err != nil || !known {
+		t.Errorf("sender was not remembered: known=%v err=%v", known, err)
+	}
+}
+
+func TestFirstMessageExistingDialog(t *testing.T) {
+	// Пустое хранилище, но в диалоге уже есть переписка
+	bot := newContactsBot(t, `{"success":true,"response":[
+		{"message_id":5,"from_id":1,"to_id":8,"message":"Готово","time":100},
+		{"message_id":11,"from_id":8,"to_id":1,"message":"Спасибо","time":200}
+	],"paging":{"page":1,"pages":1}}`)
+
+	matched := firstMessages(bot, &types.Message{FromID: 8, MessageID: 11, Time: 200})
+	if len(matched) != 0 {
+		t.Errorf("FirstMessage matched %v for an existing dialog", matched)
+	}
+}
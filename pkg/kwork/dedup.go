@@ -0,0 +1,110 @@
+package kwork
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/rtexty/gokwork/pkg/kwork/types"
+)
+
+const (
+	defaultDedupTTL  = 10 * time.Minute
+	defaultDedupSize = 10000
+)
+
+// dedupEntry запись о недавно обработанном сообщении
+type dedupEntry struct {
+	key       string
+	messageID int
+	expires   time.Time
+}
+
+// dedupCache ограниченный по размеру и времени кэш обработанных сообщений.
+// Одно входящее сообщение может прийти как new_inbox, notify и pop_up_notify;
+// кэш пропускает только первое из них.
+type dedupCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	maxSize int
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+func newDedupCache(ttl time.Duration, maxSize int) *dedupCache {
+	if ttl <= 0 {
+		ttl = defaultDedupTTL
+	}
+	if maxSize <= 0 {
+		maxSize = defaultDedupSize
+	}
+	return &dedupCache{
+		ttl:     ttl,
+		maxSize: maxSize,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// seen проверяет, обрабатывалось ли сообщение, и запоминает его.
+// Сообщения сравниваются по ID, а если у одного из них ID неизвестен —
+// по отправителю и тексту.
+func (d *dedupCache) seen(msg *types.Message) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	d.evictExpired(now)
+
+	if msg.MessageID != 0 {
+		if _, ok := d.entries[msg.Key()]; ok {
+			return true
+		}
+	}
+
+	if elem, ok := d.entries[msg.ContentKey()]; ok {
+		entry := elem.Value.(*dedupEntry)
+		// Одинаковый текст с разными известными ID — это разные сообщения
+		if entry.messageID == 0 || msg.MessageID == 0 || entry.messageID == msg.MessageID {
+			return true
+		}
+	}
+
+	if msg.MessageID != 0 {
+		d.add(msg.Key(), msg.MessageID, now)
+	}
+	d.add(msg.ContentKey(), msg.MessageID, now)
+
+	return false
+}
+
+func (d *dedupCache) add(key string, messageID int, now time.Time) {
+	if elem, ok := d.entries[key]; ok {
+		d.order.Remove(elem)
+	}
+
+	d.entries[key] = d.order.PushBack(&dedupEntry{
+		key:       key,
+		messageID: messageID,
+		expires:   now.Add(d.ttl),
+	})
+
+	for d.order.Len() > d.maxSize {
+		d.removeElement(d.order.Front())
+	}
+}
+
+// evictExpired удаляет устаревшие записи; записи упорядочены по времени добавления
+func (d *dedupCache) evictExpired(now time.Time) {
+	for elem := d.order.Front(); elem != nil; elem = d.order.Front() {
+		if elem.Value.(*dedupEntry).expires.After(now) {
+			return
+		}
+		d.removeElement(elem)
+	}
+}
+
+func (d *dedupCache) removeElement(elem *list.Element) {
+	entry := d.order.Remove(elem).(*dedupEntry)
+	delete(d.entries, entry.key)
+}
@@ -0,0 +1,98 @@
+package kwork
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rtexty/gokwork/pkg/kwork/types"
+)
+
+const (
+	testDialogsResponse = `{"success":true,"response":[
+		{"user_id":7,"username":"old","time":100,"unread_count":1,"lastMessage":{"fromUserId":7,"unread":true,"time":100}},
+		{"user_id":8,"username":"buyer","time":200,"unread_count":1,"lastMessage":{"fromUserId":8,"unread":true,"time":200}},
+		{"user_id":9,"username":"mine","time":300,"lastMessage":{"fromUserId":1,"time":300}}
+	],"paging":{"page":1,"pages":1}}`
+
+	testInboxesResponse = `{"success":true,"response":[
+		{"message_id":10,"from_id":8,"to_id":1,"message":"Здравствуйте","time":190},
+		{"message_id":11,"from_id":8,"to_id":1,"message":"Сделаете логотип?","time":200},
+		{"message_id":12,"from_id":1,"to_id":8,"message":"Да","time":210}
+	],"paging":{"page":1,"pages":1}}`
+)
+
+func newDialogsClient(t *testing.T) *Client {
+	t.Helper()
+
+	client := newRouteClient(t, map[string]string{
+		"dialogs": testDialogsResponse,
+		"inboxes": testInboxesResponse,
+	})
+	client.userID = 1
+	return client
+}
+
+func TestGetAllDialogs(t *testing.T) {
+	client := newDialogsClient(t)
+
+	dialogs, err := client.GetAllDialogs(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dialogs) != 3 || dialogs[1].Username != "buyer" || dialogs[1].LastMessage.FromUserID != 8 {
+		t.Errorf("dialogs = %+v", dialogs)
+	}
+}
+
+func TestGetDialogWithUser(t *testing.T) {
+	client := newDialogsClient(t)
+
+	messages, err := client.GetDialogWithUser(context.Background(), "buyer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 3 || messages[1].MessageID != 11 || messages[1].Message != "Сделаете логотип?" {
+		t.Errorf("messages = %+v", messages)
+	}
+}
+
+func TestNotifyEventResolvesMessage(t *testing.T) {
+	tests := []struct {
+		name  string
+		event types.Event
+	}{
+		{"notify without dialog data", &types.NotifyEvent{NewMessage: true}},
+		{"notify with dialog data", &types.NotifyEvent{NewMessage: true, DialogData: []map[string]interface{}{{"login": "buyer"}}}},
+		{"pop up", &types.PopUpNotifyEvent{Username: "buyer"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newDialogsClient(t)
+
+			messages := client.processEvent(context.Background(), tt.event)
+			if len(messages) != 1 {
+				t.Fatalf("resolved %d messages, want 1", len(messages))
+			}
+			msg := messages[0]
+			if msg.FromID != 8 || msg.MessageID != 11 || msg.Text != "Сделаете логотип?" || msg.Time != 200 {
+				t.Errorf("resolved message from %d id %d %q at %d, want the latest inbound one",
+					msg.FromID, msg.MessageID, msg.Text, msg.Time)
+			}
+		})
+	}
+}
+
+func TestAPIResponseObject(t *testing.T) {
+	client := newRouteClient(t, map[string]string{
+		"favoriteKworks": `{"success":true,"response":{"kworks":[{"id":5}],"paging":{"pages":1}}}`,
+	})
+
+	resp, err := client.apiRequest(context.Background(), "POST", "favoriteKworks", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := resp["kworks"]; !ok || !isLastPage(resp, 1) {
+		t.Errorf("object response decoded as %v", resp)
+	}
+}
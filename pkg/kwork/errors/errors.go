@@ -29,3 +29,10 @@ func (e *KworkBotError) Error() string {
 func NewKworkBotError(message string) *KworkBotError {
 	return &KworkBotError{Message: message}
 }
+
+// ErrStopPropagation возвращается обработчиком, чтобы сообщение
+// не передавалось обработчикам с меньшим приоритетом
+var ErrStopPropagation = NewKworkBotError("stop propagation")
+
+// ErrReplySuppressed возвращается при отправке ответа, который заблокировал Guard
+var ErrReplySuppressed = NewKworkBotError("reply suppressed by guard")
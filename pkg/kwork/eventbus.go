@@ -0,0 +1,220 @@
+package kwork
+
+import (
+	"context"
+	"sync"
+
+	"github.com/rtexty/gokwork/pkg/kwork/types"
+)
+
+// SlowConsumerPolicy поведение шины, когда буфер подписчика заполнен
+type SlowConsumerPolicy int
+
+const (
+	// PolicyBlock ждать, пока подписчик освободит место в буфере.
+	// Медленный подписчик задерживает доставку событий остальным.
+	PolicyBlock SlowConsumerPolicy = iota
+	// PolicyDropOldest выбросить самое старое событие из буфера
+	PolicyDropOldest
+	// PolicyDropNewest выбросить новое событие
+	PolicyDropNewest
+)
+
+const defaultSubscriptionBuffer = 100
+
+// EventFilter отбирает события для подписчика
+type EventFilter func(event types.Event) bool
+
+// SubscribeOptions параметры подписки на события
+type SubscribeOptions struct {
+	// Filter отбирает события, nil — все события
+	Filter EventFilter
+	// BufferSize размер буфера канала, по умолчанию 100
+	BufferSize int
+	// Policy поведение при заполненном буфере, по умолчанию PolicyBlock
+	Policy SlowConsumerPolicy
+}
+
+// EventTypes возвращает фильтр, пропускающий только события указанных типов
+func EventTypes(eventTypes ...string) EventFilter {
+	allowed := make(map[string]bool, len(eventTypes))
+	for _, t := range eventTypes {
+		allowed[t] = true
+	}
+	return func(event types.Event) bool {
+		return allowed[event.EventType()]
+	}
+}
+
+// Subscription подписка на события шины
+type Subscription struct {
+	bus  *EventBus
+	opts SubscribeOptions
+	ch   chan types.Event
+
+	// mu защищает отправку в ch от одновременного закрытия канала
+	mu      sync.Mutex
+	closed  bool
+	done    chan struct{}
+	once    sync.Once
+	dropped int
+}
+
+// C возвращает канал событий. Канал закрывается после отписки.
+func (s *Subscription) C() <-chan types.Event {
+	return s.ch
+}
+
+// Dropped возвращает число событий, выброшенных из-за медленного чтения
+func (s *Subscription) Dropped() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+// Unsubscribe отписывается от шины и закрывает канал событий
+func (s *Subscription) Unsubscribe() {
+	s.once.Do(func() {
+		// Сначала будим отправителя, заблокированного на полном буфере
+		close(s.done)
+
+		s.mu.Lock()
+		s.closed = true
+		close(s.ch)
+		s.mu.Unlock()
+
+		s.bus.remove(s)
+	})
+}
+
+// deliver отправляет событие подписчику согласно его политике
+func (s *Subscription) deliver(ctx context.Context, event types.Event) {
+	if s.opts.Filter != nil && !s.opts.Filter(event) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	switch s.opts.Policy {
+	case PolicyDropNewest:
+		select {
+		case s.ch <- event:
+		default:
+			s.dropped++
+		}
+
+	case PolicyDropOldest:
+		for {
+			select {
+			case s.ch <- event:
+				return
+			default:
+			}
+			select {
+			case <-s.ch:
+				s.dropped++
+			default:
+			}
+		}
+
+	default:
+		select {
+		case s.ch <- event:
+		case <-s.done:
+		case <-ctx.Done():
+		}
+	}
+}
+
+// EventBus раздает события нескольким независимым подписчикам
+type EventBus struct {
+	mu     sync.RWMutex
+	subs   map[*Subscription]struct{}
+	onIdle func()
+}
+
+// NewEventBus создает шину событий
+func NewEventBus() *EventBus {
+	return &EventBus{subs: make(map[*Subscription]struct{})}
+}
+
+// Subscribe создает подписку. Подписка закрывается при отмене ctx
+// или вызове Unsubscribe.
+func (b *EventBus) Subscribe(ctx context.Context, opts SubscribeOptions) *Subscription {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = defaultSubscriptionBuffer
+	}
+
+	sub := &Subscription{
+		bus:  b,
+		opts: opts,
+		ch:   make(chan types.Event, opts.BufferSize),
+		done: make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			sub.Unsubscribe()
+		case <-sub.done:
+		}
+	}()
+
+	return sub
+}
+
+// Publish доставляет событие всем подписчикам
+func (b *EventBus) Publish(ctx context.Context, event types.Event) {
+	b.mu.RLock()
+	subs := make([]*Subscription, 0, len(b.subs))
+	for sub := range b.subs {
+		subs = append(subs, sub)
+	}
+	b.mu.RUnlock()
+
+	for _, sub := range subs {
+		sub.deliver(ctx, event)
+	}
+}
+
+// Len возвращает число активных подписчиков
+func (b *EventBus) Len() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subs)
+}
+
+// Close закрывает все подписки
+func (b *EventBus) Close() {
+	b.mu.RLock()
+	subs := make([]*Subscription, 0, len(b.subs))
+	for sub := range b.subs {
+		subs = append(subs, sub)
+	}
+	b.mu.RUnlock()
+
+	for _, sub := range subs {
+		sub.Unsubscribe()
+	}
+}
+
+func (b *EventBus) remove(sub *Subscription) {
+	b.mu.Lock()
+	delete(b.subs, sub)
+	idle := len(b.subs) == 0
+	onIdle := b.onIdle
+	b.mu.Unlock()
+
+	if idle && onIdle != nil {
+		onIdle()
+	}
+}
@@ -0,0 +1,241 @@
+package kwork
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rtexty/gokwork/pkg/kwork/types"
+)
+
+func typingEvent(id int) types.Event {
+	return &types.TypingEvent{FromID: id}
+}
+
+// receivedIDs читает из канала все уже доставленные события
+func receivedIDs(t *testing.T, ch <-chan types.Event) []int {
+	t.Helper()
+
+	var ids []int
+	for {
+		select {
+		case event, ok := <-ch:
+			if !ok {
+				return ids
+			}
+			ids = append(ids, event.(*types.TypingEvent).FromID)
+		default:
+			return ids
+		}
+	}
+}
+
+func equalIDs(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// publishWithin публикует события и падает, если публикация заблокировалась
+func publishWithin(t *testing.T, bus *EventBus, ids ...int) {
+	t.Helper()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, id := range ids {
+			bus.Publish(context.Background(), typingEvent(id))
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Publish blocked on a slow subscriber")
+	}
+}
+
+func TestEventBusDropPolicies(t *testing.T) {
+	tests := []struct {
+		policy SlowConsumerPolicy
+		want   []int
+	}{
+		{PolicyDropOldest, []int{4, 5}},
+		{PolicyDropNewest, []int{1, 2}},
+	}
+
+	for _, tt := range tests {
+		bus := NewEventBus()
+		sub := bus.Subscribe(context.Background(), SubscribeOptions{BufferSize: 2, Policy: tt.policy})
+
+		publishWithin(t, bus, 1, 2, 3, 4, 5)
+
+		if got := receivedIDs(t, sub.C()); !equalIDs(got, tt.want) {
+			t.Errorf("policy %d: received %v, want %v", tt.policy, got, tt.want)
+		}
+		if sub.Dropped() != 3 {
+			t.Errorf("policy %d: Dropped() = %d, want 3", tt.policy, sub.Dropped())
+		}
+		sub.Unsubscribe()
+	}
+}
+
+func TestEventBusConcurrentDrop(t *testing.T) {
+	bus := NewEventBus()
+	oldest := bus.Subscribe(context.Background(), SubscribeOptions{BufferSize: 4, Policy: PolicyDropOldest})
+	newest := bus.Subscribe(context.Background(), SubscribeOptions{BufferSize: 4, Policy: PolicyDropNewest})
+
+	const publishers, perPublisher = 8, 200
+
+	var readers sync.WaitGroup
+	counts := make([]int, 2)
+	for i, sub := range []*Subscription{oldest, newest} {
+		readers.Add(1)
+		go func(i int, sub *Subscription) {
+			defer readers.Done()
+			for range sub.C() {
+				counts[i]++
+			}
+		}(i, sub)
+	}
+
+	var wg sync.WaitGroup
+	for p := 0; p < publishers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < perPublisher; i++ {
+				bus.Publish(context.Background(), typingEvent(p*perPublisher+i))
+			}
+		}(p)
+	}
+	wg.Wait()
+
+	oldest.Unsubscribe()
+	newest.Unsubscribe()
+	readers.Wait()
+
+	for i, sub := range []*Subscription{oldest, newest} {
+		if total := counts[i] + sub.Dropped(); total != publishers*perPublisher {
+			t.Errorf("subscriber %d: received %d + dropped %d, want %d in total",
+				i, counts[i], sub.Dropped(), publishers*perPublisher)
+		}
+	}
+}
+
+func TestEventBusUnsubscribeUnblocksPublish(t *testing.T) {
+	bus := NewEventBus()
+	blocked := bus.Subscribe(context.Background(), SubscribeOptions{BufferSize: 1, Policy: PolicyBlock})
+	other := bus.Subscribe(context.Background(), SubscribeOptions{BufferSize: 10, Policy: PolicyDropNewest})
+
+	bus.Publish(context.Background(), typingEvent(1))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		bus.Publish(context.Background(), typingEvent(2))
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("Publish did not block on a full PolicyBlock subscriber")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	blocked.Unsubscribe()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Publish stayed blocked after Unsubscribe")
+	}
+
+	if got := receivedIDs(t, blocked.C()); !equalIDs(got, []int{1}) {
+		t.Errorf("unsubscribed channel had %v, want [1]", got)
+	}
+	if got := receivedIDs(t, other.C()); !equalIDs(got, []int{1, 2}) {
+		t.Errorf("other subscriber received %v, want [1 2]", got)
+	}
+	if bus.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", bus.Len())
+	}
+}
+
+func TestEventsDoesNotBlockOtherSubscribers(t *testing.T) {
+	client := newRouteClient(t, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := client.Events(ctx)
+	all := client.Subscribe(ctx, SubscribeOptions{BufferSize: 2 * defaultSubscriptionBuffer})
+
+	ids := make([]int, defaultSubscriptionBuffer+10)
+	for i := range ids {
+		ids[i] = i
+	}
+	publishWithin(t, client.bus, ids...)
+
+	if got := receivedIDs(t, all.C()); len(got) != len(ids) {
+		t.Errorf("subscriber received %d events, want %d", len(got), len(ids))
+	}
+	got := receivedIDs(t, events)
+	if len(got) != defaultSubscriptionBuffer || got[0] != 10 {
+		t.Errorf("Events kept %d events starting at %v, want the newest %d", len(got), got[:1], defaultSubscriptionBuffer)
+	}
+}
+
+func TestListenerStopsWithLastSubscriber(t *testing.T) {
+	client := newRouteClient(t, nil)
+
+	listening := func() bool {
+		client.mu.Lock()
+		defer client.mu.Unlock()
+		return client.listenerCancel != nil
+	}
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	defer cancel2()
+
+	first := client.Subscribe(ctx1, SubscribeOptions{})
+	client.Subscribe(ctx2, SubscribeOptions{})
+	if !listening() {
+		t.Fatal("listener not started by Subscribe")
+	}
+
+	cancel1()
+	<-waitClosed(first.C())
+	if !listening() {
+		t.Fatal("listener stopped while a subscriber is left")
+	}
+
+	cancel2()
+	deadline := time.Now().Add(time.Second)
+	for listening() {
+		if time.Now().After(deadline) {
+			t.Fatal("listener still running after the last subscriber left")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if client.bus.Len() != 0 {
+		t.Errorf("bus has %d subscribers, want 0", client.bus.Len())
+	}
+}
+
+// waitClosed возвращает канал, который закрывается после закрытия ch
+func waitClosed(ch <-chan types.Event) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		for range ch {
+		}
+		close(done)
+	}()
+	return done
+}
@@ -0,0 +1,144 @@
+package kwork
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rtexty/gokwork/pkg/kwork/types"
+)
+
+// GetFavoriteKworks получает все избранные кворки
+func (c *Client) GetFavoriteKworks(ctx context.Context) ([]types.KworkObject, error) {
+	return c.getKworksList(ctx, "favoriteKworks")
+}
+
+// AddFavoriteKwork добавляет кворк в избранное
+func (c *Client) AddFavoriteKwork(ctx context.Context, kworkID int) error {
+	return c.toggleByID(ctx, "favoriteKworkAdd", kworkID)
+}
+
+// RemoveFavoriteKwork удаляет кворк из избранного
+func (c *Client) RemoveFavoriteKwork(ctx context.Context, kworkID int) error {
+	return c.toggleByID(ctx, "favoriteKworkRemove", kworkID)
+}
+
+// GetFavoriteSellers получает всех избранных продавцов
+func (c *Client) GetFavoriteSellers(ctx context.Context) ([]types.Worker, error) {
+	token, err := c.GetToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var sellers []types.Worker
+	page := 1
+
+	for {
+		params := map[string]string{
+			"token": token,
+			"page":  fmt.Sprintf("%d", page),
+		}
+
+		resp, err := c.apiRequest(ctx, "POST", "favoriteSellers", params)
+		if err != nil {
+			return nil, err
+		}
+
+		var pageSellers []types.Worker
+		if err := decodeResponse(resp["users"], &pageSellers); err != nil {
+			return nil, err
+		}
+		if len(pageSellers) == 0 {
+			break
+		}
+
+		sellers = append(sellers, pageSellers...)
+
+		if isLastPage(resp, page) {
+			break
+		}
+		page++
+	}
+
+	return sellers, nil
+}
+
+// AddFavoriteSeller добавляет продавца в избранное
+func (c *Client) AddFavoriteSeller(ctx context.Context, userID int) error {
+	return c.toggleByID(ctx, "favoriteSellerAdd", userID)
+}
+
+// RemoveFavoriteSeller удаляет продавца из избранного
+func (c *Client) RemoveFavoriteSeller(ctx context.Context, userID int) error {
+	return c.toggleByID(ctx, "favoriteSellerRemove", userID)
+}
+
+// GetHiddenKworks получает все скрытые кворки
+func (c *Client) GetHiddenKworks(ctx context.Context) ([]types.KworkObject, error) {
+	return c.getKworksList(ctx, "hiddenKworks")
+}
+
+// HideKwork скрывает кворк из выдачи
+func (c *Client) HideKwork(ctx context.Context, kworkID int) error {
+	return c.toggleByID(ctx, "hideKwork", kworkID)
+}
+
+// UnhideKwork возвращает скрытый кворк в выдачу
+func (c *Client) UnhideKwork(ctx context.Context, kworkID int) error {
+	return c.toggleByID(ctx, "unhideKwork", kworkID)
+}
+
+// getKworksList получает все страницы списка кворков
+func (c *Client) getKworksList(ctx context.Context, apiMethod string) ([]types.KworkObject, error) {
+	token, err := c.GetToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var kworks []types.KworkObject
+	page := 1
+
+	for {
+		params := map[string]string{
+			"token": token,
+			"page":  fmt.Sprintf("%d", page),
+		}
+
+		resp, err := c.apiRequest(ctx, "POST", apiMethod, params)
+		if err != nil {
+			return nil, err
+		}
+
+		var pageKworks []types.KworkObject
+		if err := decodeResponse(resp["kworks"], &pageKworks); err != nil {
+			return nil, err
+		}
+		if len(pageKworks) == 0 {
+			break
+		}
+
+		kworks = append(kworks, pageKworks...)
+
+		if isLastPage(resp, page) {
+			break
+		}
+		page++
+	}
+
+	return kworks, nil
+}
+
+// toggleByID выполняет метод API, принимающий только идентификатор объекта
+func (c *Client) toggleByID(ctx context.Context, apiMethod string, id int) error {
+	token, err := c.GetToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	params := map[string]string{
+		"token": token,
+		"id":    fmt.Sprintf("%d", id),
+	}
+
+	_, err = c.apiRequest(ctx, "POST", apiMethod, params)
+	return err
+}
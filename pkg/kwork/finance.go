@@ -0,0 +1,223 @@
+package kwork
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rtexty/gokwork/pkg/kwork/errors"
+	"github.com/rtexty/gokwork/pkg/kwork/types"
+)
+
+// TransactionsParams параметры для получения истории операций
+type TransactionsParams struct {
+	Page  int
+	From  time.Time
+	To    time.Time
+	Types []types.OperationType
+}
+
+// GetBalance получает текущий баланс пользователя
+func (c *Client) GetBalance(ctx context.Context) (*types.Balance, error) {
+	me, err := c.GetMe(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.Balance{
+		TotalAmount: me.TotalAmount,
+		HoldAmount:  me.HoldAmount,
+		FreeAmount:  me.FreeAmount,
+		Currency:    me.Currency,
+	}, nil
+}
+
+// GetTransactions получает одну страницу истории операций
+func (c *Client) GetTransactions(ctx context.Context, params TransactionsParams) (*types.TransactionsPage, error) {
+	token, err := c.GetToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	page := params.Page
+	if page < 1 {
+		page = 1
+	}
+
+	apiParams := map[string]string{
+		"token": token,
+		"page":  fmt.Sprintf("%d", page),
+	}
+	if !params.From.IsZero() {
+		apiParams["date_from"] = fmt.Sprintf("%d", params.From.Unix())
+	}
+	if !params.To.IsZero() {
+		apiParams["date_to"] = fmt.Sprintf("%d", params.To.Unix())
+	}
+	if len(params.Types) > 0 {
+		opTypes := make([]string, len(params.Types))
+		for i, t := range params.Types {
+			opTypes[i] = string(t)
+		}
+		apiParams["types"] = strings.Join(opTypes, ",")
+	}
+
+	resp, err := c.apiRequest(ctx, "POST", "operations", apiParams)
+	if err != nil {
+		return nil, err
+	}
+
+	var transactions []types.Transaction
+	if err := decodeResponse(resp["operations"], &transactions); err != nil {
+		return nil, err
+	}
+
+	result := &types.TransactionsPage{
+		Transactions: transactions,
+		Page:         page,
+		Pages:        page,
+	}
+	if paging, ok := resp["paging"].(map[string]interface{}); ok {
+		if pages, ok := paging["pages"].(float64); ok {
+			result.Pages = int(pages)
+		}
+	}
+
+	return result, nil
+}
+
+// GetAllTransactions получает все операции, подходящие под фильтры
+func (c *Client) GetAllTransactions(ctx context.Context, params TransactionsParams) ([]types.Transaction, error) {
+	var transactions []types.Transaction
+	params.Page = 1
+
+	for {
+		page, err := c.GetTransactions(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+
+		transactions = append(transactions, page.Transactions...)
+
+		if len(page.Transactions) == 0 || page.Page >= page.Pages {
+			break
+		}
+		params.Page++
+	}
+
+	return transactions, nil
+}
+
+// GetMonthlyStatement формирует выписку за указанный месяц. Если операции за месяц
+// прошли в нескольких валютах, возвращает ошибку: используйте GetMonthlyStatements.
+func (c *Client) GetMonthlyStatement(ctx context.Context, year int, month time.Month, loc *time.Location) (*types.MonthlyStatement, error) {
+	statements, err := c.GetMonthlyStatements(ctx, year, month, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(statements) {
+	case 0:
+		return &types.MonthlyStatement{Year: year, Month: int(month)}, nil
+	case 1:
+		return &statements[0], nil
+	}
+
+	currencies := make([]string, len(statements))
+	for i, statement := range statements {
+		currencies[i] = statement.Currency
+	}
+	return nil, errors.NewKworkError(fmt.Sprintf(
+		"transactions for %d-%02d are in several currencies (%s), use GetMonthlyStatements",
+		year, month, strings.Join(currencies, ", ")))
+}
+
+// GetMonthlyStatements формирует выписки за указанный месяц, по одной на каждую валюту
+func (c *Client) GetMonthlyStatements(ctx context.Context, year int, month time.Month, loc *time.Location) ([]types.MonthlyStatement, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+
+	from := time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	to := from.AddDate(0, 1, 0).Add(-time.Second)
+
+	transactions, err := c.GetAllTransactions(ctx, TransactionsParams{From: from, To: to})
+	if err != nil {
+		return nil, err
+	}
+
+	// API может вернуть операции на границе периода, оставляем только запрошенный месяц
+	var statements []types.MonthlyStatement
+	for _, statement := range BuildMonthlyStatements(transactions, loc) {
+		if statement.Year == year && statement.Month == int(month) {
+			statements = append(statements, statement)
+		}
+	}
+
+	return statements, nil
+}
+
+// BuildMonthlyStatements группирует операции по месяцам и валютам и считает итоги.
+// Операции в разных валютах попадают в разные выписки одного месяца.
+// Net — заработок за месяц: оплаты заказов и бонусы за вычетом возвратов и комиссий.
+// Выводы средств учитываются отдельно и на Net не влияют.
+func BuildMonthlyStatements(transactions []types.Transaction, loc *time.Location) []types.MonthlyStatement {
+	if loc == nil {
+		loc = time.Local
+	}
+
+	byMonth := make(map[string]*types.MonthlyStatement)
+	var keys []string
+
+	for _, tx := range transactions {
+		t := time.Unix(int64(tx.Time), 0).In(loc)
+		key := t.Format("2006-01") + " " + tx.Currency
+
+		statement, ok := byMonth[key]
+		if !ok {
+			statement = &types.MonthlyStatement{
+				Year:     t.Year(),
+				Month:    int(t.Month()),
+				Currency: tx.Currency,
+			}
+			byMonth[key] = statement
+			keys = append(keys, key)
+		}
+
+		amount := tx.Amount
+		if amount < 0 {
+			amount = -amount
+		}
+
+		switch tx.Type {
+		case types.OperationOrderPayment:
+			statement.Income += amount
+		case types.OperationBonus:
+			statement.Bonuses += amount
+		case types.OperationRefund:
+			statement.Refunds += amount
+		case types.OperationCommission:
+			statement.Commissions += amount
+		case types.OperationWithdrawal:
+			statement.Withdrawals += amount
+		}
+
+		statement.Transactions = append(statement.Transactions, tx)
+	}
+
+	sort.Strings(keys)
+
+	statements := make([]types.MonthlyStatement, 0, len(keys))
+	for _, key := range keys {
+		statement := byMonth[key]
+		statement.Net = statement.Income + statement.Bonuses - statement.Refunds - statement.Commissions
+		sort.Slice(statement.Transactions, func(i, j int) bool {
+			return statement.Transactions[i].Time < statement.Transactions[j].Time
+		})
+		statements = append(statements, *statement)
+	}
+
+	return statements
+}
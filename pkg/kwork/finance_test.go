@@ -0,0 +1,158 @@
+package kwork
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rtexty/gokwork/pkg/kwork/types"
+)
+
+// routeTransport отвечает заранее заданным JSON по имени метода API
+// и запоминает вызванные методы
+type routeTransport struct {
+	responses map[string]string
+
+	mu      sync.Mutex
+	methods []string
+}
+
+func (t *routeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	method := path.Base(req.URL.Path)
+
+	t.mu.Lock()
+	t.methods = append(t.methods, method)
+	t.mu.Unlock()
+
+	body, ok := t.responses[method]
+	if !ok {
+		body = `{"success":true,"response":{}}`
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+// calls возвращает число вызовов метода API
+func (t *routeTransport) calls(method string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	n := 0
+	for _, m := range t.methods {
+		if m == method {
+			n++
+		}
+	}
+	return n
+}
+
+func newRouteClient(t *testing.T, responses map[string]string) *Client {
+	t.Helper()
+
+	client, err := NewClient(Config{Login: "login", Password: "password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+
+	client.httpClient.Transport = &routeTransport{responses: responses}
+	client.token = "token"
+	return client
+}
+
+func newRouteBot(t *testing.T, responses map[string]string) (*Bot, *routeTransport) {
+	t.Helper()
+
+	bot, err := NewBot(Config{Login: "login", Password: "password", SelfID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(bot.Close)
+
+	transport := &routeTransport{responses: responses}
+	bot.httpClient.Transport = transport
+	bot.token = "token"
+	return bot, transport
+}
+
+func TestBuildMonthlyStatementsSplitsCurrencies(t *testing.T) {
+	march := time.Date(2025, time.March, 10, 12, 0, 0, 0, time.UTC)
+	april := march.AddDate(0, 1, 0)
+
+	statements := BuildMonthlyStatements([]types.Transaction{
+		{Type: types.OperationOrderPayment, Amount: 1000, Currency: "RUB", Time: int(march.Unix())},
+		{Type: types.OperationCommission, Amount: -200, Currency: "RUB", Time: int(march.Unix())},
+		{Type: types.OperationOrderPayment, Amount: 50, Currency: "USD", Time: int(march.Unix())},
+		{Type: types.OperationOrderPayment, Amount: 700, Currency: "RUB", Time: int(april.Unix())},
+	}, time.UTC)
+
+	if len(statements) != 3 {
+		t.Fatalf("got %d statements, want 3: %+v", len(statements), statements)
+	}
+
+	want := []struct {
+		month    int
+		currency string
+		income   int
+		net      int
+	}{
+		{3, "RUB", 1000, 800},
+		{3, "USD", 50, 50},
+		{4, "RUB", 700, 700},
+	}
+	for i, w := range want {
+		s := statements[i]
+		if s.Month != w.month || s.Currency != w.currency || s.Income != w.income || s.Net != w.net {
+			t.Errorf("statement %d = %d %s income %d net %d, want %d %s income %d net %d",
+				i, s.Month, s.Currency, s.Income, s.Net, w.month, w.currency, w.income, w.net)
+		}
+	}
+}
+
+func TestGetMonthlyStatementSelectsMonth(t *testing.T) {
+	// Операция февраля на границе периода не должна стать выпиской за март
+	client := newRouteClient(t, map[string]string{
+		"operations": `{"success":true,"response":{"operations":[
+			{"id":1,"type":"order_payment","amount":300,"currency":"RUB","time":1740787100},
+			{"id":2,"type":"order_payment","amount":500,"currency":"RUB","time":1741600000}
+		],"paging":{"pages":1}}}`,
+	})
+
+	statement, err := client.GetMonthlyStatement(context.Background(), 2025, time.March, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if statement.Year != 2025 || statement.Month != 3 || statement.Income != 500 {
+		t.Errorf("statement = %d-%d income %d, want 2025-3 income 500", statement.Year, statement.Month, statement.Income)
+	}
+}
+
+func TestGetMonthlyStatementSeveralCurrencies(t *testing.T) {
+	client := newRouteClient(t, map[string]string{
+		"operations": `{"success":true,"response":{"operations":[
+			{"id":1,"type":"order_payment","amount":500,"currency":"RUB","time":1741600000},
+			{"id":2,"type":"order_payment","amount":10,"currency":"USD","time":1741600000}
+		],"paging":{"pages":1}}}`,
+	})
+
+	if _, err := client.GetMonthlyStatement(context.Background(), 2025, time.March, time.UTC); err == nil {
+		t.Error("GetMonthlyStatement mixed currencies without error")
+	}
+
+	statements, err := client.GetMonthlyStatements(context.Background(), 2025, time.March, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statements) != 2 {
+		t.Fatalf("got %d statements, want 2", len(statements))
+	}
+}
@@ -0,0 +1,176 @@
+package kwork
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/rtexty/gokwork/pkg/kwork/errors"
+	"github.com/rtexty/gokwork/pkg/kwork/types"
+)
+
+// StateNone состояние пользователя, с которым не ведется многошаговый диалог
+const StateNone = ""
+
+// FSM конечный автомат диалогов: хранит состояние разговора с каждым пользователем
+type FSM struct {
+	storage StateStorage
+	timeout time.Duration
+
+	// mu делает чтение и изменение состояния атомарными
+	mu          sync.Mutex
+	transitions map[string]map[string]bool
+}
+
+// NewFSM создает автомат диалогов. Если storage не задан, состояния хранятся
+// в памяти. Диалог, в котором не было переходов дольше timeout, сбрасывается
+// в StateNone; нулевой timeout отключает сброс.
+func NewFSM(storage StateStorage, timeout time.Duration) *FSM {
+	if storage == nil {
+		storage = NewMemoryStateStorage()
+	}
+	return &FSM{
+		storage:     storage,
+		timeout:     timeout,
+		transitions: make(map[string]map[string]bool),
+	}
+}
+
+// Allow разрешает переходы из состояния from в состояния to.
+// Пока не объявлен ни один переход, разрешены любые. Сброс в StateNone
+// разрешен всегда.
+func (f *FSM) Allow(from string, to ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.transitions[from] == nil {
+		f.transitions[from] = make(map[string]bool)
+	}
+	for _, state := range to {
+		f.transitions[from][state] = true
+	}
+}
+
+// State возвращает текущее состояние диалога с пользователем
+func (f *FSM) State(userID int) (ConversationState, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.load(userID)
+}
+
+// Transition переводит диалог с пользователем в состояние to, сохраняя данные диалога
+func (f *FSM) Transition(userID int, to string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	current, err := f.load(userID)
+	if err != nil {
+		return err
+	}
+
+	if !f.allowed(current.State, to) {
+		return errors.NewKworkBotError(fmt.Sprintf("transition from %q to %q is not allowed", current.State, to))
+	}
+
+	if to == StateNone {
+		return f.storage.Delete(userID)
+	}
+
+	current.State = to
+	current.UpdatedAt = time.Now()
+	return f.storage.Save(userID, current)
+}
+
+// SetData сохраняет значение в данных диалога с пользователем
+func (f *FSM) SetData(userID int, key, value string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	current, err := f.load(userID)
+	if err != nil {
+		return err
+	}
+
+	data := make(map[string]string, len(current.Data)+1)
+	for k, v := range current.Data {
+		data[k] = v
+	}
+	data[key] = value
+
+	current.Data = data
+	current.UpdatedAt = time.Now()
+	return f.storage.Save(userID, current)
+}
+
+// Reset завершает диалог с пользователем и удаляет его данные
+func (f *FSM) Reset(userID int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.storage.Delete(userID)
+}
+
+// load читает состояние и сбрасывает устаревший диалог
+func (f *FSM) load(userID int) (ConversationState, error) {
+	state, ok, err := f.storage.Load(userID)
+	if err != nil {
+		return ConversationState{}, err
+	}
+	if !ok {
+		return ConversationState{State: StateNone}, nil
+	}
+
+	if f.timeout > 0 && time.Since(state.UpdatedAt) > f.timeout {
+		log.Printf("Conversation with user %d expired in state %q", userID, state.State)
+		if err := f.storage.Delete(userID); err != nil {
+			return ConversationState{}, err
+		}
+		return ConversationState{State: StateNone}, nil
+	}
+
+	return state, nil
+}
+
+func (f *FSM) allowed(from, to string) bool {
+	if len(f.transitions) == 0 || to == StateNone {
+		return true
+	}
+	return f.transitions[from][to]
+}
+
+type conversationKey struct{}
+
+// withConversation сохраняет в контексте состояние, с которым сообщение попало к обработчикам
+func withConversation(ctx context.Context, state ConversationState) context.Context {
+	return context.WithValue(ctx, conversationKey{}, state)
+}
+
+// ConversationFromContext возвращает состояние диалога на момент получения
+// сообщения. Переходы, сделанные обработчиками, в нем не отражаются.
+func ConversationFromContext(ctx context.Context) (ConversationState, bool) {
+	state, ok := ctx.Value(conversationKey{}).(ConversationState)
+	return state, ok
+}
+
+// InState пропускает сообщения пользователей, находящихся в одном из состояний.
+// Состояние проверяется на момент получения сообщения, поэтому переход,
+// сделанный одним обработчиком, не передает то же сообщение обработчику
+// следующего состояния.
+func InState(states ...string) Filter {
+	allowed := make(map[string]bool, len(states))
+	for _, state := range states {
+		allowed[state] = true
+	}
+	return func(ctx context.Context, b *Bot, msg *types.Message) bool {
+		state, ok := ConversationFromContext(ctx)
+		if !ok {
+			var err error
+			if state, err = b.fsm.State(msg.FromID); err != nil {
+				log.Printf("Failed to load conversation state: %v", err)
+				return false
+			}
+		}
+		return allowed[state.State]
+	}
+}
@@ -0,0 +1,170 @@
+package kwork
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rtexty/gokwork/pkg/kwork/types"
+)
+
+// syncMarkKey ключ отметки времени последней сверки всего аккаунта
+const syncMarkKey = 0
+
+// gapFiller досылает входящие сообщения, пропущенные пока WebSocket был отключен.
+// Для каждого диалога хранится отметка последнего выданного сообщения,
+// поэтому каждое сообщение выдается ровно один раз.
+type gapFiller struct {
+	client *Client
+	store  HighWaterStore
+	mu     sync.Mutex
+
+	// untimed ID сообщений, выданных без времени сервера, по отправителям.
+	// Отметку по ним сдвинуть нельзя, поэтому сверка пропускает их по ID.
+	untimed map[int]map[int]bool
+}
+
+func newGapFiller(c *Client) *gapFiller {
+	return &gapFiller{client: c, store: c.highWater, untimed: make(map[int]map[int]bool)}
+}
+
+// observe обновляет отметку по сообщению, полученному через WebSocket.
+// Возвращает false, если сообщение уже было выдано раньше.
+func (g *gapFiller) observe(msg *types.Message) bool {
+	if msg.FromID == 0 {
+		return true
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// Без времени сервера сообщение нельзя сравнить с отметкой и нельзя сдвигать ее:
+	// запоминаем ID, чтобы сверка не выдала его повторно
+	if msg.Time == 0 {
+		if msg.MessageID == 0 {
+			return true
+		}
+		ids := g.untimed[msg.FromID]
+		if ids == nil {
+			ids = make(map[int]bool)
+			g.untimed[msg.FromID] = ids
+		}
+		if ids[msg.MessageID] {
+			return false
+		}
+		ids[msg.MessageID] = true
+		return true
+	}
+
+	mark, ok, err := g.store.Load(msg.FromID)
+	if err != nil {
+		log.Printf("Failed to load high-water mark: %v", err)
+		return true
+	}
+	if ok && !mark.Before(msg.Time, msg.MessageID) {
+		// Без ID сообщения повтор определить нельзя, просто не сдвигаем отметку
+		return msg.MessageID == 0
+	}
+
+	g.save(msg.FromID, HighWaterMark{Time: msg.Time, MessageID: msg.MessageID})
+	return true
+}
+
+// reconcile сверяет диалоги с отметками и выдает пропущенные входящие сообщения
+func (g *gapFiller) reconcile(ctx context.Context, emit func(*types.Message)) error {
+	selfID, err := g.client.selfID(ctx)
+	if err != nil {
+		return err
+	}
+
+	syncStarted := int(time.Now().Unix())
+
+	dialogs, err := g.client.GetAllDialogs(ctx)
+	if err != nil {
+		return err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	syncMark, synced, err := g.store.Load(syncMarkKey)
+	if err != nil {
+		return err
+	}
+
+	for _, dialog := range dialogs {
+		if dialog.UserID == 0 {
+			continue
+		}
+
+		mark, ok, err := g.store.Load(dialog.UserID)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			if !synced {
+				// Первая сверка: не выдаем всю историю, только запоминаем позицию
+				g.save(dialog.UserID, HighWaterMark{Time: dialog.Time})
+				continue
+			}
+			// Новый диалог, появившийся после прошлой сверки
+			mark = HighWaterMark{Time: syncMark.Time}
+		}
+
+		if dialog.Time <= mark.Time {
+			continue
+		}
+
+		messages, err := g.client.GetDialogWithUser(ctx, dialog.Username)
+		if err != nil {
+			return err
+		}
+
+		sort.Slice(messages, func(i, j int) bool {
+			if messages[i].Time != messages[j].Time {
+				return messages[i].Time < messages[j].Time
+			}
+			return messages[i].MessageID < messages[j].MessageID
+		})
+
+		untimed := g.untimed[dialog.UserID]
+		for _, m := range messages {
+			// Сообщение из истории покрывается отметкой, помнить его ID больше не нужно
+			delivered := untimed[m.MessageID]
+			delete(untimed, m.MessageID)
+
+			if m.FromID == selfID || !mark.Before(m.Time, m.MessageID) {
+				continue
+			}
+
+			mark = HighWaterMark{Time: m.Time, MessageID: m.MessageID}
+			g.save(dialog.UserID, mark)
+			if delivered {
+				// Уже выдано через WebSocket без времени сервера
+				continue
+			}
+			emit(g.client.messageFromInbox(m))
+		}
+		if len(untimed) == 0 {
+			delete(g.untimed, dialog.UserID)
+		}
+
+		// Диалог мог обновиться нашим ответом: сдвигаем отметку, чтобы не
+		// запрашивать его повторно при следующей сверке
+		if mark.Time < dialog.Time {
+			g.save(dialog.UserID, HighWaterMark{Time: dialog.Time})
+		}
+	}
+
+	g.save(syncMarkKey, HighWaterMark{Time: syncStarted})
+	return nil
+}
+
+func (g *gapFiller) save(userID int, mark HighWaterMark) {
+	if err := g.store.Save(userID, mark); err != nil {
+		log.Printf("Failed to save high-water mark: %v", err)
+	}
+}
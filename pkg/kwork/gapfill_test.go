@@ -0,0 +1,64 @@
+package kwork
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rtexty/gokwork/pkg/kwork/types"
+)
+
+// reconcileIDs выполняет сверку и возвращает ID выданных сообщений
+func reconcileIDs(t *testing.T, gaps *gapFiller) []int {
+	t.Helper()
+
+	var ids []int
+	err := gaps.reconcile(context.Background(), func(msg *types.Message) {
+		ids = append(ids, msg.MessageID)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ids
+}
+
+func TestGapFillerReconcile(t *testing.T) {
+	client := newDialogsClient(t)
+	gaps := newGapFiller(client)
+
+	// Первая сверка только запоминает позицию диалогов
+	if ids := reconcileIDs(t, gaps); len(ids) != 0 {
+		t.Fatalf("first reconcile emitted %v, want nothing", ids)
+	}
+
+	// Отметка отстала: досылаются входящие сообщения после нее
+	gaps.save(8, HighWaterMark{Time: 150})
+	if ids := reconcileIDs(t, gaps); !equalIDs(ids, []int{10, 11}) {
+		t.Errorf("reconcile emitted %v, want [10 11]", ids)
+	}
+	if ids := reconcileIDs(t, gaps); len(ids) != 0 {
+		t.Errorf("repeated reconcile emitted %v, want nothing", ids)
+	}
+}
+
+func TestGapFillerSkipsUntimedLiveMessage(t *testing.T) {
+	client := newDialogsClient(t)
+	gaps := newGapFiller(client)
+	reconcileIDs(t, gaps)
+	gaps.save(8, HighWaterMark{Time: 150})
+
+	// Сообщение пришло через WebSocket без времени сервера
+	live := &types.Message{FromID: 8, MessageID: 11}
+	if !gaps.observe(live) {
+		t.Fatal("live message without time was not emitted")
+	}
+	if gaps.observe(live) {
+		t.Error("repeated live message was emitted twice")
+	}
+
+	if ids := reconcileIDs(t, gaps); !equalIDs(ids, []int{10}) {
+		t.Errorf("reconcile emitted %v, want only the missed message [10]", ids)
+	}
+	if len(gaps.untimed) != 0 {
+		t.Errorf("untimed IDs left after reconcile: %v", gaps.untimed)
+	}
+}
@@ -0,0 +1,387 @@
+package kwork
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/rtexty/gokwork/pkg/kwork/errors"
+	"github.com/rtexty/gokwork/pkg/kwork/types"
+)
+
+const (
+	defaultGuardMaxReplies       = 5
+	defaultGuardWindow           = 10 * time.Minute
+	defaultGuardLoopResponseTime = 3 * time.Second
+	defaultGuardLoopThreshold    = 3
+	defaultGuardDialogCacheTTL   = 10 * time.Minute
+
+	// guardDialogRefreshInterval минимальный интервал между попытками загрузить
+	// список диалогов
+	guardDialogRefreshInterval = 30 * time.Second
+)
+
+// EscalationReason причина, по которой Guard передал диалог человеку
+type EscalationReason string
+
+const (
+	// EscalationRateLimit в диалоге превышен лимит ответов за окно
+	EscalationRateLimit EscalationReason = "rate_limit"
+	// EscalationLoop собеседник похож на другого бота: бот и он отвечают друг другу по кругу
+	EscalationLoop EscalationReason = "loop"
+)
+
+// Escalation диалог, в котором бот перестал отвечать
+type Escalation struct {
+	Reason EscalationReason
+	// Message входящее сообщение, на котором сработала защита
+	Message *types.Message
+	// Reply ответ, который не был отправлен. Пустой для EscalationLoop и когда
+	// лимит сработал на статусе "печатает", до отправки текста.
+	Reply string
+}
+
+// GuardConfig настройки защиты от спама и зацикливания
+type GuardConfig struct {
+	// MaxReplies максимальное число ответов в одном диалоге за Window, по умолчанию 5
+	MaxReplies int
+	// Window окно для MaxReplies и время, на которое диалог замолкает
+	// после обнаружения зацикливания. По умолчанию 10 минут.
+	Window time.Duration
+	// LoopResponseTime входящее сообщение, пришедшее быстрее этого после ответа бота,
+	// считается автоматическим. По умолчанию 3 секунды.
+	LoopResponseTime time.Duration
+	// LoopThreshold число автоматических ответов подряд, после которого диалог
+	// считается зацикленным. По умолчанию 3.
+	LoopThreshold int
+	// DialogCacheTTL период обновления списка диалогов, из которого берутся
+	// BlockedByUser и AllowedDialog. По умолчанию 10 минут.
+	DialogCacheTTL time.Duration
+	// OnEscalate вызывается вместо ответа, когда сработал лимит или обнаружено
+	// зацикливание. Для каждого диалога и причины — не чаще раза за Window.
+	OnEscalate func(ctx context.Context, escalation Escalation)
+}
+
+// dialogGuard состояние защиты одного диалога
+type dialogGuard struct {
+	replies      []time.Time
+	lastReply    time.Time
+	lastIncoming time.Time
+	lastText     string
+	streak       int
+	mutedUntil   time.Time
+	escalated    map[EscalationReason]time.Time
+	lastSeen     time.Time
+}
+
+// Guard ограничивает ответы бота: лимит ответов на диалог, защита от
+// зацикливания с другими ботами и учет блокировки диалога собеседником.
+// Подключается как middleware: bot.Use(guard.Middleware()).
+type Guard struct {
+	client *Client
+	cfg    GuardConfig
+
+	mu        sync.Mutex
+	dialogs   map[int]*dialogGuard
+	lastPrune time.Time
+
+	// refreshMu не дает загружать список диалогов одновременно
+	refreshMu      sync.Mutex
+	dialogInfo     map[int]types.Dialog
+	dialogsLoaded  time.Time
+	dialogsChecked time.Time
+}
+
+// NewGuard создает защиту ответов
+func NewGuard(client *Client, cfg GuardConfig) *Guard {
+	if cfg.MaxReplies <= 0 {
+		cfg.MaxReplies = defaultGuardMaxReplies
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = defaultGuardWindow
+	}
+	if cfg.LoopResponseTime <= 0 {
+		cfg.LoopResponseTime = defaultGuardLoopResponseTime
+	}
+	if cfg.LoopThreshold <= 0 {
+		cfg.LoopThreshold = defaultGuardLoopThreshold
+	}
+	if cfg.DialogCacheTTL <= 0 {
+		cfg.DialogCacheTTL = defaultGuardDialogCacheTTL
+	}
+
+	return &Guard{
+		client:     client,
+		cfg:        cfg,
+		dialogs:    make(map[int]*dialogGuard),
+		dialogInfo: make(map[int]types.Dialog),
+	}
+}
+
+// Middleware возвращает middleware, которая не передает обработчикам сообщения
+// из заблокированных и зацикленных диалогов, а ответы сверх лимита заменяет
+// вызовом OnEscalate. Заблокированный ответ возвращает обработчику
+// errors.ErrReplySuppressed. Сообщение, подходящее нескольким обработчикам,
+// проверяется один раз, а каждый вызов обработчика считается одним ответом,
+// сколько бы частей он ни отправил.
+func (g *Guard) Middleware() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, msg *types.Message) error {
+			if !oncePerMessage(ctx, g, func() bool { return g.admit(ctx, msg) }) {
+				return nil
+			}
+
+			return next(ctx, msg.WithSender(&guardedSender{
+				MessageSender: msg.Sender(),
+				guard:         g,
+				msg:           msg,
+			}))
+		}
+	}
+}
+
+// admit проверяет входящее сообщение: диалог не заблокирован и не зациклен
+func (g *Guard) admit(ctx context.Context, msg *types.Message) bool {
+	if !g.dialogAllowed(ctx, msg.FromID) {
+		log.Printf("Dialog with user %d is blocked, not replying", msg.FromID)
+		return false
+	}
+
+	if g.observe(msg) {
+		log.Printf("Reply loop detected in dialog with user %d", msg.FromID)
+		g.escalate(ctx, Escalation{Reason: EscalationLoop, Message: msg})
+		return false
+	}
+
+	return true
+}
+
+// dialogAllowed проверяет по списку диалогов, что собеседник не заблокировал
+// диалог и ему можно писать. Собеседник, которого нет в списке, не блокируется.
+func (g *Guard) dialogAllowed(ctx context.Context, userID int) bool {
+	dialog, ok, loaded := g.cachedDialog(userID)
+	if time.Since(loaded) > g.cfg.DialogCacheTTL || !ok {
+		g.refreshDialogs(ctx)
+		dialog, ok, _ = g.cachedDialog(userID)
+	}
+
+	if !ok {
+		return true
+	}
+	return !dialog.BlockedByUser && dialog.AllowedDialog
+}
+
+func (g *Guard) cachedDialog(userID int) (types.Dialog, bool, time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	dialog, ok := g.dialogInfo[userID]
+	return dialog, ok, g.dialogsLoaded
+}
+
+// refreshDialogs загружает список диалогов
+func (g *Guard) refreshDialogs(ctx context.Context) {
+	g.refreshMu.Lock()
+	defer g.refreshMu.Unlock()
+
+	// Список мог обновить другой обработчик, пока мы ждали. Неудачные попытки
+	// тоже учитываются, чтобы не запрашивать все диалоги на каждое сообщение,
+	// пока API недоступно.
+	g.mu.Lock()
+	recent := time.Since(g.dialogsChecked) < guardDialogRefreshInterval
+	if !recent {
+		g.dialogsChecked = time.Now()
+	}
+	g.mu.Unlock()
+	if recent {
+		return
+	}
+
+	dialogs, err := g.client.GetAllDialogs(ctx)
+	if err != nil {
+		// Не блокируем ответы из-за ошибки API
+		log.Printf("Failed to load dialogs for guard: %v", err)
+		return
+	}
+
+	info := make(map[int]types.Dialog, len(dialogs))
+	for _, dialog := range dialogs {
+		info[dialog.UserID] = dialog
+	}
+
+	g.mu.Lock()
+	g.dialogInfo = info
+	g.dialogsLoaded = time.Now()
+	g.mu.Unlock()
+}
+
+// observe учитывает входящее сообщение и проверяет, не зациклился ли диалог.
+// Сообщение считается автоматическим, если пришло сразу после ответа бота
+// или повторяет предыдущее сообщение собеседника.
+func (g *Guard) observe(msg *types.Message) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	g.prune(now)
+
+	d := g.dialog(msg.FromID)
+	d.lastSeen = now
+
+	if now.Before(d.mutedUntil) {
+		return true
+	}
+
+	repliedSinceLast := d.lastReply.After(d.lastIncoming)
+	fast := repliedSinceLast && now.Sub(d.lastReply) <= g.cfg.LoopResponseTime
+	repeated := repliedSinceLast && msg.Text == d.lastText
+
+	if fast || repeated {
+		d.streak++
+	} else {
+		d.streak = 0
+	}
+	d.lastText = msg.Text
+	d.lastIncoming = now
+
+	if d.streak >= g.cfg.LoopThreshold {
+		d.streak = 0
+		d.mutedUntil = now.Add(g.cfg.Window)
+		return true
+	}
+	return false
+}
+
+// allowReply проверяет лимит ответов в диалоге и учитывает ответ
+func (g *Guard) allowReply(userID int) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	d := g.dialog(userID)
+
+	recent := d.replies[:0]
+	for _, t := range d.replies {
+		if now.Sub(t) < g.cfg.Window {
+			recent = append(recent, t)
+		}
+	}
+	d.replies = recent
+
+	if len(d.replies) >= g.cfg.MaxReplies {
+		return false
+	}
+
+	d.replies = append(d.replies, now)
+	d.lastReply = now
+	d.lastSeen = now
+	return true
+}
+
+// markReply отмечает отправку следующей части уже разрешенного ответа
+func (g *Guard) markReply(userID int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	d := g.dialog(userID)
+	d.lastReply = time.Now()
+	d.lastSeen = d.lastReply
+}
+
+// escalate вызывает OnEscalate не чаще раза за Window для диалога и причины
+func (g *Guard) escalate(ctx context.Context, escalation Escalation) {
+	if g.cfg.OnEscalate == nil {
+		return
+	}
+
+	g.mu.Lock()
+	d := g.dialog(escalation.Message.FromID)
+	now := time.Now()
+	if last, ok := d.escalated[escalation.Reason]; ok && now.Sub(last) < g.cfg.Window {
+		g.mu.Unlock()
+		return
+	}
+	d.escalated[escalation.Reason] = now
+	g.mu.Unlock()
+
+	g.cfg.OnEscalate(ctx, escalation)
+}
+
+// dialog возвращает состояние диалога, вызывается под g.mu
+func (g *Guard) dialog(userID int) *dialogGuard {
+	d, ok := g.dialogs[userID]
+	if !ok {
+		d = &dialogGuard{escalated: make(map[EscalationReason]time.Time)}
+		g.dialogs[userID] = d
+	}
+	return d
+}
+
+// prune удаляет неактивные диалоги не чаще раза за Window, вызывается под g.mu
+func (g *Guard) prune(now time.Time) {
+	if now.Sub(g.lastPrune) < g.cfg.Window {
+		return
+	}
+	g.lastPrune = now
+
+	for id, d := range g.dialogs {
+		if now.Sub(d.lastSeen) > 2*g.cfg.Window && now.After(d.mutedUntil) {
+			delete(g.dialogs, id)
+		}
+	}
+}
+
+// guardedSender отправляет ответы одного вызова обработчика через Guard
+type guardedSender struct {
+	types.MessageSender
+	guard *Guard
+	msg   *types.Message
+
+	// Лимит проверяется при первом SetTyping или SendMessage, остальные вызовы
+	// того же обработчика получают то же решение
+	mu      sync.Mutex
+	decided bool
+	allowed bool
+}
+
+// decide возвращает решение по ответу и true, если оно принято этим вызовом.
+// text — текст ответа для OnEscalate, пустой, если решение принято по SetTyping.
+func (s *guardedSender) decide(ctx context.Context, userID int, text string) (bool, bool) {
+	s.mu.Lock()
+	first := !s.decided
+	if first {
+		s.decided = true
+		s.allowed = s.guard.allowReply(userID)
+	}
+	allowed := s.allowed
+	s.mu.Unlock()
+
+	if !allowed && first {
+		log.Printf("Reply limit reached in dialog with user %d", userID)
+		s.guard.escalate(ctx, Escalation{Reason: EscalationRateLimit, Message: s.msg, Reply: text})
+	}
+	return allowed, first
+}
+
+// SetTyping показывает статус "печатает", только если ответ разрешен:
+// иначе собеседник видел бы набор текста, за которым ничего не приходит
+func (s *guardedSender) SetTyping(ctx context.Context, recipientID int) error {
+	if allowed, _ := s.decide(ctx, recipientID, ""); !allowed {
+		return errors.ErrReplySuppressed
+	}
+	return s.MessageSender.SetTyping(ctx, recipientID)
+}
+
+// SendMessage отправляет сообщение, если не превышен лимит ответов в диалоге
+func (s *guardedSender) SendMessage(ctx context.Context, userID int, text string) error {
+	allowed, first := s.decide(ctx, userID, text)
+	if !allowed {
+		return errors.ErrReplySuppressed
+	}
+
+	// Время ответа для поиска зацикливания — момент отправки, а не начала набора
+	if !first {
+		s.guard.markReply(userID)
+	}
+	return s.MessageSender.SendMessage(ctx, userID, text)
+}
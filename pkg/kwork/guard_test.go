@@ -0,0 +1,106 @@
+package kwork
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+	"time"
+
+	"github.com/rtexty/gokwork/pkg/kwork/errors"
+	"github.com/rtexty/gokwork/pkg/kwork/types"
+)
+
+// runGuarded прогоняет сообщения через Guard и обработчик, который показывает
+// набор текста и отвечает. Возвращает ошибки обработчика.
+func runGuarded(bot *Bot, guard *Guard, messages ...*types.Message) []error {
+	var results []error
+	bot.Use(guard.Middleware())
+	bot.Handle(nil, func(ctx context.Context, msg *types.Message) error {
+		err := msg.Sender().SetTyping(ctx, msg.FromID)
+		if err == nil {
+			err = msg.FastAnswer(ctx, "Ответ")
+		}
+		results = append(results, err)
+		return err
+	})
+
+	routes := bot.buildRoutes()
+	for _, msg := range messages {
+		bot.dispatch(context.Background(), routes, types.NewMessage(bot, msg.FromID, msg.Text, 1, 0, "", nil))
+	}
+	return results
+}
+
+func TestGuardSuppressesTyping(t *testing.T) {
+	bot, transport := newRouteBot(t, map[string]string{
+		"dialogs": `{"success":true,"response":[{"user_id":8,"username":"buyer","allowedDialog":true}],"paging":{"pages":1}}`,
+	})
+
+	var escalations []Escalation
+	guard := NewGuard(bot.Client, GuardConfig{
+		MaxReplies: 1,
+		OnEscalate: func(ctx context.Context, e Escalation) {
+			escalations = append(escalations, e)
+		},
+	})
+
+	results := runGuarded(bot, guard,
+		&types.Message{FromID: 8, Text: "Здравствуйте"},
+		&types.Message{FromID: 8, Text: "Есть вопрос"},
+	)
+
+	if len(results) != 2 || results[0] != nil || !stderrors.Is(results[1], errors.ErrReplySuppressed) {
+		t.Fatalf("handler results = %v, want [nil ErrReplySuppressed]", results)
+	}
+	if n := transport.calls("typing"); n != 1 {
+		t.Errorf("typing sent %d times, want 1: a suppressed reply must not show typing", n)
+	}
+	if n := transport.calls("inboxCreate"); n != 1 {
+		t.Errorf("message sent %d times, want 1", n)
+	}
+	if n := transport.calls("dialogs"); n != 1 {
+		t.Errorf("dialogs requested %d times, want 1", n)
+	}
+	if len(escalations) != 1 || escalations[0].Reason != EscalationRateLimit || escalations[0].Reply != "" {
+		t.Errorf("escalations = %+v, want one rate limit without reply text", escalations)
+	}
+}
+
+func TestGuardBlockedDialog(t *testing.T) {
+	bot, transport := newRouteBot(t, map[string]string{
+		"dialogs": `{"success":true,"response":[{"user_id":8,"username":"buyer","blocked_by_user":true,"allowedDialog":true}],"paging":{"pages":1}}`,
+	})
+	guard := NewGuard(bot.Client, GuardConfig{})
+
+	if results := runGuarded(bot, guard, &types.Message{FromID: 8, Text: "Здравствуйте"}); len(results) != 0 {
+		t.Errorf("handler called for a blocked dialog: %v", results)
+	}
+	if n := transport.calls("typing") + transport.calls("inboxCreate"); n != 0 {
+		t.Errorf("%d requests sent to a blocked dialog", n)
+	}
+}
+
+func TestGuardSendDecidesWithoutTyping(t *testing.T) {
+	bot, transport := newRouteBot(t, nil)
+	guard := NewGuard(bot.Client, GuardConfig{MaxReplies: 1, LoopResponseTime: time.Nanosecond})
+
+	var results []error
+	bot.Use(guard.Middleware())
+	bot.Handle(nil, func(ctx context.Context, msg *types.Message) error {
+		err := msg.FastAnswer(ctx, "Ответ")
+		results = append(results, err)
+		return err
+	})
+
+	routes := bot.buildRoutes()
+	for _, text := range []string{"раз", "два"} {
+		bot.dispatch(context.Background(), routes, types.NewMessage(bot, 8, text, 1, 0, "", nil))
+	}
+
+	if len(results) != 2 || results[0] != nil || !stderrors.Is(results[1], errors.ErrReplySuppressed) {
+		t.Fatalf("handler results = %v, want [nil ErrReplySuppressed]", results)
+	}
+	if n := transport.calls("inboxCreate"); n != 1 {
+		t.Errorf("message sent %d times, want 1", n)
+	}
+}
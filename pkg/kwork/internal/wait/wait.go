@@ -0,0 +1,25 @@
+// Package wait содержит общие для пакетов kwork ожидания с учетом контекста
+package wait
+
+import (
+	"context"
+	"time"
+)
+
+// Sleep ждет указанное время или отмену контекста.
+// Возвращает false, если контекст был отменен.
+func Sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
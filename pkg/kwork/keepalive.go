@@ -0,0 +1,164 @@
+package kwork
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	defaultPingInterval = 30 * time.Second
+	defaultPongTimeout  = 75 * time.Second
+	controlWriteTimeout = 10 * time.Second
+)
+
+// keepaliveSettings параметры проверки живости соединения WebSocket
+type keepaliveSettings struct {
+	pingInterval time.Duration
+	pongTimeout  time.Duration
+	idleTimeout  time.Duration
+}
+
+func newKeepaliveSettings(cfg Config) keepaliveSettings {
+	s := keepaliveSettings{
+		pingInterval: cfg.PingInterval,
+		pongTimeout:  cfg.PongTimeout,
+		idleTimeout:  cfg.IdleTimeout,
+	}
+	if s.pingInterval == 0 {
+		s.pingInterval = defaultPingInterval
+	}
+	if s.pongTimeout <= 0 {
+		s.pongTimeout = defaultPongTimeout
+	}
+	// Ответ на ping должен успеть прийти до истечения дедлайна чтения
+	if s.pingInterval > 0 && s.pongTimeout <= s.pingInterval {
+		s.pongTimeout = s.pingInterval * 2
+	}
+	return s
+}
+
+// heartbeat следит за живостью одного соединения WebSocket:
+// отправляет ping, продлевает дедлайн чтения при любой активности
+// и закрывает соединение, если сервер перестал отвечать или присылать данные.
+type heartbeat struct {
+	conn     *websocket.Conn
+	settings keepaliveSettings
+
+	mu           sync.Mutex
+	lastActivity time.Time
+	failure      error
+}
+
+func startHeartbeat(conn *websocket.Conn, settings keepaliveSettings, done <-chan struct{}) *heartbeat {
+	h := &heartbeat{
+		conn:         conn,
+		settings:     settings,
+		lastActivity: time.Now(),
+	}
+
+	h.extendDeadline()
+
+	conn.SetPongHandler(func(string) error {
+		return h.extendDeadline()
+	})
+
+	conn.SetPingHandler(func(appData string) error {
+		if err := h.extendDeadline(); err != nil {
+			return err
+		}
+		err := conn.WriteControl(websocket.PongMessage, []byte(appData), time.Now().Add(controlWriteTimeout))
+		if err == websocket.ErrCloseSent {
+			return nil
+		}
+		return err
+	})
+
+	go h.run(done)
+
+	return h
+}
+
+// extendDeadline продлевает дедлайн чтения. Без ping сервер может молчать
+// сколько угодно, поэтому дедлайн не ставится: молчание ограничивает только IdleTimeout.
+func (h *heartbeat) extendDeadline() error {
+	if h.settings.pingInterval < 0 {
+		return h.conn.SetReadDeadline(time.Time{})
+	}
+	return h.conn.SetReadDeadline(time.Now().Add(h.settings.pongTimeout))
+}
+
+// dataReceived отмечает получение данных от сервера
+func (h *heartbeat) dataReceived() {
+	h.mu.Lock()
+	h.lastActivity = time.Now()
+	h.mu.Unlock()
+
+	if err := h.extendDeadline(); err != nil {
+		log.Printf("Failed to extend websocket read deadline: %v", err)
+	}
+}
+
+// wrapError дополняет ошибку чтения причиной, по которой heartbeat закрыл соединение
+func (h *heartbeat) wrapError(err error) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.failure != nil {
+		return h.failure
+	}
+	return err
+}
+
+func (h *heartbeat) fail(err error) {
+	h.mu.Lock()
+	if h.failure == nil {
+		h.failure = err
+	}
+	h.mu.Unlock()
+
+	h.conn.Close()
+}
+
+func (h *heartbeat) run(done <-chan struct{}) {
+	interval := h.settings.pingInterval
+	if interval <= 0 || (h.settings.idleTimeout > 0 && h.settings.idleTimeout < interval) {
+		interval = h.settings.idleTimeout
+	}
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		if h.settings.idleTimeout > 0 {
+			h.mu.Lock()
+			idle := time.Since(h.lastActivity)
+			h.mu.Unlock()
+
+			if idle > h.settings.idleTimeout {
+				h.fail(fmt.Errorf("no websocket data for %s", idle.Round(time.Second)))
+				return
+			}
+		}
+
+		if h.settings.pingInterval > 0 {
+			err := h.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(controlWriteTimeout))
+			if err != nil {
+				h.fail(fmt.Errorf("failed to send websocket ping: %w", err))
+				return
+			}
+		}
+	}
+}
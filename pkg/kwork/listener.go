@@ -0,0 +1,128 @@
+package kwork
+
+import (
+	"context"
+	"log"
+
+	"github.com/rtexty/gokwork/pkg/kwork/types"
+)
+
+// Subscribe подписывается на события WebSocket.
+// Все подписчики клиента используют одно соединение: оно открывается
+// при первой подписке и закрывается, когда отписывается последний подписчик.
+func (c *Client) Subscribe(ctx context.Context, opts SubscribeOptions) *Subscription {
+	sub := c.bus.Subscribe(ctx, opts)
+	c.ensureListener()
+	return sub
+}
+
+// ensureListener запускает общий слушатель WebSocket, если он еще не запущен
+func (c *Client) ensureListener() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.listenerCancel != nil || c.bus.Len() == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.listenerCancel = cancel
+	c.listenerGen++
+	c.listenerErr = nil
+	gen := c.listenerGen
+
+	go func() {
+		defer cancel()
+
+		publish := func(event types.Event) {
+			c.bus.Publish(ctx, event)
+		}
+
+		var err error
+		if c.replayFile != "" {
+			err = c.replay(ctx, publish)
+		} else {
+			err = c.listenEvents(ctx, c.runConnectHooks, publish)
+		}
+
+		c.mu.Lock()
+		current := c.listenerGen == gen
+		if current {
+			c.listenerCancel = nil
+			if ctx.Err() == nil {
+				c.listenerErr = err
+			}
+		}
+		c.mu.Unlock()
+
+		// Слушатель завершился сам (исчерпаны попытки переподключения
+		// или закончилась запись): закрываем подписки, чтобы подписчики узнали об этом
+		if current && ctx.Err() == nil {
+			if err != nil {
+				log.Printf("Event listener stopped: %v", err)
+			}
+			c.bus.Close()
+		}
+	}()
+}
+
+// stopListener останавливает общий слушатель WebSocket
+func (c *Client) stopListener() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.listenerCancel != nil {
+		c.listenerCancel()
+		c.listenerCancel = nil
+	}
+}
+
+// listenerError возвращает ошибку, с которой завершился общий слушатель
+func (c *Client) listenerError() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.listenerErr
+}
+
+// addConnectHook регистрирует функцию, вызываемую после каждого подключения
+// к WebSocket до чтения событий. Возвращает функцию удаления хука.
+func (c *Client) addConnectHook(hook func(context.Context)) func() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.hookSeq++
+	id := c.hookSeq
+	c.connectHooks[id] = hook
+
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.connectHooks, id)
+	}
+}
+
+func (c *Client) runConnectHooks(ctx context.Context) {
+	c.mu.Lock()
+	hooks := make([]func(context.Context), 0, len(c.connectHooks))
+	for _, hook := range c.connectHooks {
+		hooks = append(hooks, hook)
+	}
+	c.mu.Unlock()
+
+	for _, hook := range hooks {
+		hook(ctx)
+	}
+}
+
+func (c *Client) setConnected(connected bool) {
+	c.mu.Lock()
+	c.connected = connected
+	c.mu.Unlock()
+}
+
+// isConnected проверяет, установлено ли сейчас соединение WebSocket
+func (c *Client) isConnected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.connected
+}
@@ -0,0 +1,258 @@
+package kwork
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rtexty/gokwork/pkg/kwork/errors"
+	"github.com/rtexty/gokwork/pkg/kwork/types"
+)
+
+// Middleware оборачивает вызов обработчика общей логикой
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// chain оборачивает обработчик в middleware: первая middleware — внешняя
+func chain(handler HandlerFunc, middleware []Middleware) HandlerFunc {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+	return handler
+}
+
+// Group группа обработчиков с общими middleware
+type Group struct {
+	bot        *Bot
+	parent     *Group
+	middleware []Middleware
+}
+
+// Use добавляет middleware группы. Они выполняются после middleware бота
+// и родительских групп.
+func (g *Group) Use(middleware ...Middleware) {
+	g.middleware = append(g.middleware, middleware...)
+}
+
+// Group создает вложенную группу
+func (g *Group) Group(middleware ...Middleware) *Group {
+	return &Group{bot: g.bot, parent: g, middleware: middleware}
+}
+
+// Handle регистрирует обработчик в группе, см. Bot.Handle
+func (g *Group) Handle(filter Filter, handler HandlerFunc) *Route {
+	route := g.bot.Handle(filter, handler)
+	route.group = g
+	return route
+}
+
+// OnState регистрирует в группе обработчик состояния, см. Bot.OnState
+func (g *Group) OnState(state string, handler HandlerFunc) *Route {
+	return g.Handle(InState(state), handler)
+}
+
+// chain возвращает middleware группы вместе с родительскими
+func (g *Group) chain() []Middleware {
+	if g == nil {
+		return nil
+	}
+	return append(g.parent.chain(), g.middleware...)
+}
+
+// Logging записывает в лог каждый вызов обработчика и его результат
+func Logging() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, msg *types.Message) error {
+			start := time.Now()
+			err := next(ctx, msg)
+			if err != nil && !stderrors.Is(err, errors.ErrStopPropagation) {
+				log.Printf("Handler for message from %d failed in %s: %v", msg.FromID, time.Since(start), err)
+			} else {
+				log.Printf("Handled message from %d in %s", msg.FromID, time.Since(start))
+			}
+			return err
+		}
+	}
+}
+
+// Recover перехватывает панику в обработчике и возвращает ее как ошибку
+func Recover() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, msg *types.Message) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("Handler panic: %v\n%s", r, debug.Stack())
+					err = errors.NewKworkBotError(fmt.Sprintf("handler panic: %v", r))
+				}
+			}()
+			return next(ctx, msg)
+		}
+	}
+}
+
+// Timeout ограничивает время работы обработчика через контекст
+func Timeout(timeout time.Duration) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, msg *types.Message) error {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			return next(ctx, msg)
+		}
+	}
+}
+
+// Blacklist пропускает сообщения от указанных пользователей без вызова обработчика
+func Blacklist(userIDs ...int) Middleware {
+	blocked := make(map[int]bool, len(userIDs))
+	for _, id := range userIDs {
+		blocked[id] = true
+	}
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, msg *types.Message) error {
+			if blocked[msg.FromID] {
+				log.Printf("Ignoring message from blacklisted user %d", msg.FromID)
+				return nil
+			}
+			return next(ctx, msg)
+		}
+	}
+}
+
+// messageScope решения middleware, общие для всех обработчиков одного сообщения.
+// Middleware оборачивает каждый обработчик отдельно, а решения вроде лимита
+// сообщений должны приниматься один раз на сообщение.
+type messageScope struct {
+	mu        sync.Mutex
+	decisions map[any]bool
+}
+
+type messageScopeKey struct{}
+
+// withMessageScope добавляет в контекст место для решений middleware о сообщении
+func withMessageScope(ctx context.Context) context.Context {
+	return context.WithValue(ctx, messageScopeKey{}, &messageScope{decisions: make(map[any]bool)})
+}
+
+// oncePerMessage вызывает decide один раз на сообщение для middleware key,
+// остальные обработчики сообщения получают сохраненное решение.
+// Вне Bot.dispatch decide вызывается при каждом вызове.
+func oncePerMessage(ctx context.Context, key any, decide func() bool) bool {
+	scope, ok := ctx.Value(messageScopeKey{}).(*messageScope)
+	if !ok {
+		return decide()
+	}
+
+	scope.mu.Lock()
+	defer scope.mu.Unlock()
+
+	if decision, ok := scope.decisions[key]; ok {
+		return decision
+	}
+	decision := decide()
+	scope.decisions[key] = decision
+	return decision
+}
+
+// RateLimit пропускает не больше limit сообщений одного пользователя за window.
+// Остальные сообщения не передаются обработчику. Сообщение, подходящее
+// нескольким обработчикам, учитывается один раз.
+func RateLimit(limit int, window time.Duration) Middleware {
+	var (
+		mu   sync.Mutex
+		hits = make(map[int][]time.Time)
+	)
+
+	allow := func(userID int) bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		now := time.Now()
+		recent := hits[userID][:0]
+		for _, t := range hits[userID] {
+			if now.Sub(t) < window {
+				recent = append(recent, t)
+			}
+		}
+
+		if len(recent) >= limit {
+			hits[userID] = recent
+			return false
+		}
+
+		hits[userID] = append(recent, now)
+		return true
+	}
+
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, msg *types.Message) error {
+			// Ключ решения — мьютекс этого экземпляра RateLimit
+			allowed := oncePerMessage(ctx, &mu, func() bool {
+				if !allow(msg.FromID) {
+					log.Printf("Rate limit exceeded for user %d", msg.FromID)
+					return false
+				}
+				return true
+			})
+			if !allowed {
+				return nil
+			}
+			return next(ctx, msg)
+		}
+	}
+}
+
+// MetricsRecorder получает результат каждого вызова обработчика
+type MetricsRecorder interface {
+	RecordHandler(msg *types.Message, duration time.Duration, err error)
+}
+
+// Metrics передает длительность и результат вызовов обработчика в recorder
+func Metrics(recorder MetricsRecorder) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, msg *types.Message) error {
+			start := time.Now()
+			err := next(ctx, msg)
+			recorder.RecordHandler(msg, time.Since(start), err)
+			return err
+		}
+	}
+}
+
+// HandlerMetrics простые счетчики вызовов обработчиков
+type HandlerMetrics struct {
+	handled  atomic.Int64
+	failed   atomic.Int64
+	duration atomic.Int64
+}
+
+// RecordHandler учитывает вызов обработчика
+func (m *HandlerMetrics) RecordHandler(msg *types.Message, duration time.Duration, err error) {
+	m.handled.Add(1)
+	if err != nil && !stderrors.Is(err, errors.ErrStopPropagation) {
+		m.failed.Add(1)
+	}
+	m.duration.Add(int64(duration))
+}
+
+// Handled возвращает число вызовов обработчиков
+func (m *HandlerMetrics) Handled() int64 {
+	return m.handled.Load()
+}
+
+// Failed возвращает число вызовов, завершившихся ошибкой
+func (m *HandlerMetrics) Failed() int64 {
+	return m.failed.Load()
+}
+
+// AverageDuration возвращает среднее время работы обработчика
+func (m *HandlerMetrics) AverageDuration() time.Duration {
+	handled := m.handled.Load()
+	if handled == 0 {
+		return 0
+	}
+	return time.Duration(m.duration.Load() / handled)
+}
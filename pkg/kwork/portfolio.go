@@ -0,0 +1,221 @@
+package kwork
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rtexty/gokwork/pkg/kwork/errors"
+	"github.com/rtexty/gokwork/pkg/kwork/types"
+)
+
+// PortfolioParams параметры для получения портфолио пользователя
+type PortfolioParams struct {
+	UserID     int
+	CategoryID int
+	Page       int
+}
+
+// PortfolioItemParams параметры для создания и редактирования работы в портфолио
+type PortfolioItemParams struct {
+	Title       string
+	Description string
+	CategoryID  int
+	OrderID     int
+	ImageIDs    []int
+	VideoIDs    []int
+}
+
+// GetPortfolio получает одну страницу портфолио пользователя
+func (c *Client) GetPortfolio(ctx context.Context, params PortfolioParams) ([]types.PortfolioItem, error) {
+	token, err := c.GetToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	apiParams := map[string]string{
+		"token": token,
+		"id":    fmt.Sprintf("%d", params.UserID),
+	}
+	if params.CategoryID > 0 {
+		apiParams["category_id"] = fmt.Sprintf("%d", params.CategoryID)
+	}
+	if params.Page > 0 {
+		apiParams["page"] = fmt.Sprintf("%d", params.Page)
+	}
+
+	resp, err := c.apiRequest(ctx, "POST", "userPortfolio", apiParams)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []types.PortfolioItem
+	if err := decodeResponse(resp["portfolio_list"], &items); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// GetAllPortfolio получает все работы из портфолио пользователя
+func (c *Client) GetAllPortfolio(ctx context.Context, userID int) ([]types.PortfolioItem, error) {
+	token, err := c.GetToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []types.PortfolioItem
+	page := 1
+
+	for {
+		params := map[string]string{
+			"token": token,
+			"id":    fmt.Sprintf("%d", userID),
+			"page":  fmt.Sprintf("%d", page),
+		}
+
+		resp, err := c.apiRequest(ctx, "POST", "userPortfolio", params)
+		if err != nil {
+			return nil, err
+		}
+
+		var pageItems []types.PortfolioItem
+		if err := decodeResponse(resp["portfolio_list"], &pageItems); err != nil {
+			return nil, err
+		}
+		if len(pageItems) == 0 {
+			break
+		}
+
+		items = append(items, pageItems...)
+
+		if isLastPage(resp, page) {
+			break
+		}
+		page++
+	}
+
+	return items, nil
+}
+
+// CreatePortfolioItem добавляет работу в свое портфолио
+func (c *Client) CreatePortfolioItem(ctx context.Context, params PortfolioItemParams) (*types.PortfolioItem, error) {
+	if params.Title == "" {
+		return nil, errors.NewKworkError("portfolio item title is required")
+	}
+	if params.CategoryID == 0 {
+		return nil, errors.NewKworkError("portfolio item category is required")
+	}
+
+	return c.savePortfolioItem(ctx, "portfolioCreate", 0, params)
+}
+
+// UpdatePortfolioItem редактирует работу в своем портфолио
+func (c *Client) UpdatePortfolioItem(ctx context.Context, itemID int, params PortfolioItemParams) (*types.PortfolioItem, error) {
+	return c.savePortfolioItem(ctx, "portfolioEdit", itemID, params)
+}
+
+// DeletePortfolioItem удаляет работу из своего портфолио
+func (c *Client) DeletePortfolioItem(ctx context.Context, itemID int) error {
+	token, err := c.GetToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	params := map[string]string{
+		"token": token,
+		"id":    fmt.Sprintf("%d", itemID),
+	}
+
+	_, err = c.apiRequest(ctx, "POST", "portfolioDelete", params)
+	return err
+}
+
+// UploadPortfolioImage загружает изображение для портфолио
+func (c *Client) UploadPortfolioImage(ctx context.Context, fileName string, file io.Reader) (*types.PortfolioImage, error) {
+	resp, err := c.uploadPortfolioFile(ctx, "portfolioUploadImage", fileName, file)
+	if err != nil {
+		return nil, err
+	}
+
+	var image types.PortfolioImage
+	if err := decodeResponse(resp, &image); err != nil {
+		return nil, err
+	}
+
+	return &image, nil
+}
+
+// UploadPortfolioVideo загружает видео для портфолио
+func (c *Client) UploadPortfolioVideo(ctx context.Context, fileName string, file io.Reader) (*types.PortfolioVideo, error) {
+	resp, err := c.uploadPortfolioFile(ctx, "portfolioUploadVideo", fileName, file)
+	if err != nil {
+		return nil, err
+	}
+
+	var video types.PortfolioVideo
+	if err := decodeResponse(resp, &video); err != nil {
+		return nil, err
+	}
+
+	return &video, nil
+}
+
+func (c *Client) uploadPortfolioFile(ctx context.Context, apiMethod, fileName string, file io.Reader) (map[string]interface{}, error) {
+	token, err := c.GetToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	params := map[string]string{
+		"token": token,
+	}
+
+	return c.apiMultipartRequest(ctx, apiMethod, params, "file", fileName, file)
+}
+
+func (c *Client) savePortfolioItem(ctx context.Context, apiMethod string, itemID int, params PortfolioItemParams) (*types.PortfolioItem, error) {
+	token, err := c.GetToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	apiParams := map[string]string{
+		"token":       token,
+		"title":       params.Title,
+		"description": params.Description,
+		"images":      joinIDs(params.ImageIDs),
+		"videos":      joinIDs(params.VideoIDs),
+	}
+	if itemID > 0 {
+		apiParams["id"] = fmt.Sprintf("%d", itemID)
+	}
+	if params.CategoryID > 0 {
+		apiParams["category_id"] = fmt.Sprintf("%d", params.CategoryID)
+	}
+	if params.OrderID > 0 {
+		apiParams["order_id"] = fmt.Sprintf("%d", params.OrderID)
+	}
+
+	resp, err := c.apiRequest(ctx, "POST", apiMethod, apiParams)
+	if err != nil {
+		return nil, err
+	}
+
+	var item types.PortfolioItem
+	if err := decodeResponse(resp, &item); err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+// joinIDs объединяет идентификаторы через запятую
+func joinIDs(ids []int) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = fmt.Sprintf("%d", id)
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,179 @@
+package kwork
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/rtexty/gokwork/pkg/kwork/types"
+)
+
+const (
+	defaultPresenceTTL       = 2 * time.Minute
+	presenceFetchConcurrency = 5
+	minKeepOnlineInterval    = 30 * time.Second
+)
+
+// presenceCache хранит последние известные статусы пользователей
+type presenceCache struct {
+	mu      sync.RWMutex
+	ttl     time.Duration
+	entries map[int]types.Presence
+}
+
+func newPresenceCache(ttl time.Duration) *presenceCache {
+	if ttl <= 0 {
+		ttl = defaultPresenceTTL
+	}
+	return &presenceCache{
+		ttl:     ttl,
+		entries: make(map[int]types.Presence),
+	}
+}
+
+// get возвращает статус, если он еще не устарел
+func (p *presenceCache) get(userID int) (types.Presence, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	presence, ok := p.entries[userID]
+	if !ok || time.Since(presence.UpdatedAt) > p.ttl {
+		return types.Presence{}, false
+	}
+	return presence, true
+}
+
+func (p *presenceCache) set(presence types.Presence) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.entries[presence.UserID] = presence
+}
+
+// markOnline отмечает пользователя онлайн по событию WebSocket
+func (p *presenceCache) markOnline(userID int) {
+	if userID == 0 {
+		return
+	}
+	now := time.Now()
+	p.set(types.Presence{
+		UserID:    userID,
+		Online:    true,
+		LiveDate:  int(now.Unix()),
+		UpdatedAt: now,
+	})
+}
+
+// GetUserPresence получает онлайн-статусы пользователей.
+// Свежие статусы берутся из кэша, который обновляется событиями WebSocket,
+// для остальных параллельно запрашивается профиль: один GetUser на пользователя.
+func (c *Client) GetUserPresence(ctx context.Context, userIDs ...int) (map[int]types.Presence, error) {
+	result := make(map[int]types.Presence, len(userIDs))
+
+	var missing []int
+	for _, id := range userIDs {
+		if presence, ok := c.presence.get(id); ok {
+			result[id] = presence
+		} else {
+			missing = append(missing, id)
+		}
+	}
+
+	if len(missing) == 0 {
+		return result, nil
+	}
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+	)
+	sem := make(chan struct{}, presenceFetchConcurrency)
+
+	for _, id := range missing {
+		wg.Add(1)
+		go func(userID int) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				return
+			}
+
+			user, err := c.GetUser(ctx, userID)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+
+			presence := presenceFromUser(userID, user)
+			c.presence.set(presence)
+			result[userID] = presence
+		}(id)
+	}
+
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	return result, nil
+}
+
+// IsUserOnline проверяет, находится ли пользователь онлайн
+func (c *Client) IsUserOnline(ctx context.Context, userID int) (bool, error) {
+	presence, err := c.GetUserPresence(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return presence[userID].Online, nil
+}
+
+// KeepOnline периодически обновляет статус онлайн до отмены контекста.
+// После отмены контекста устанавливает статус оффлайн.
+func (c *Client) KeepOnline(ctx context.Context, interval time.Duration) error {
+	if interval < minKeepOnlineInterval {
+		interval = minKeepOnlineInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.SetOnline(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Failed to set online status: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			offlineCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := c.SetOffline(offlineCtx); err != nil {
+				log.Printf("Failed to set offline status: %v", err)
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func presenceFromUser(userID int, user *types.User) types.Presence {
+	return types.Presence{
+		UserID:    userID,
+		Online:    user.Online,
+		LiveDate:  user.LiveDate,
+		UpdatedAt: time.Now(),
+	}
+}
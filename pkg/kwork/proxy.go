@@ -0,0 +1,274 @@
+package kwork
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"golang.org/x/net/proxy"
+)
+
+// newTransports создает HTTP транспорт и WebSocket dialer, работающие через
+// один и тот же прокси. Поддерживаются схемы http, https, socks5, socks5h,
+// socks4 и socks4a, логин и пароль берутся из URL.
+func newTransports(proxyURL *url.URL) (*http.Transport, *websocket.Dialer, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	wsDialer := &websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: 45 * time.Second,
+	}
+
+	if proxyURL == nil {
+		return transport, wsDialer, nil
+	}
+
+	switch proxyURL.Scheme {
+	case "http", "https":
+		// Логин и пароль из URL передаются в заголовке Proxy-Authorization
+		transport.Proxy = http.ProxyURL(proxyURL)
+		wsDialer.Proxy = nil
+		wsDialer.NetDialContext = (&httpConnectDialer{proxyURL: proxyURL}).DialContext
+		return transport, wsDialer, nil
+	}
+
+	dialer, err := newProxyDialer(proxyURL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	wsDialer.Proxy = nil
+	wsDialer.NetDialContext = dialer.DialContext
+
+	return transport, wsDialer, nil
+}
+
+// newContextDialer создает dialer, устанавливающий TCP соединения через прокси
+func newContextDialer(proxyURL *url.URL) (proxy.ContextDialer, error) {
+	switch proxyURL.Scheme {
+	case "http", "https":
+		return &httpConnectDialer{proxyURL: proxyURL}, nil
+	default:
+		return newProxyDialer(proxyURL)
+	}
+}
+
+// newProxyDialer создает dialer для SOCKS прокси
+func newProxyDialer(proxyURL *url.URL) (proxy.ContextDialer, error) {
+	switch proxyURL.Scheme {
+	case "socks5", "socks5h":
+		var auth *proxy.Auth
+		if proxyURL.User != nil {
+			password, _ := proxyURL.User.Password()
+			auth = &proxy.Auth{
+				User:     proxyURL.User.Username(),
+				Password: password,
+			}
+		}
+
+		dialer, err := proxy.SOCKS5("tcp", proxyURL.Host, auth, proxy.Direct)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create SOCKS5 dialer: %w", err)
+		}
+
+		contextDialer, ok := dialer.(proxy.ContextDialer)
+		if !ok {
+			return nil, fmt.Errorf("SOCKS5 dialer does not support context")
+		}
+		return contextDialer, nil
+
+	case "socks4", "socks4a":
+		return &socks4Dialer{
+			proxyAddr:     proxyURL.Host,
+			userID:        proxyURL.User.Username(),
+			resolveRemote: proxyURL.Scheme == "socks4a",
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", proxyURL.Scheme)
+	}
+}
+
+// httpConnectDialer устанавливает туннель через HTTP(S) прокси методом CONNECT
+type httpConnectDialer struct {
+	proxyURL *url.URL
+	dialer   net.Dialer
+}
+
+// DialContext устанавливает соединение с addr через прокси
+func (d *httpConnectDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	proxyAddr := d.proxyURL.Host
+	if d.proxyURL.Port() == "" {
+		port := "80"
+		if d.proxyURL.Scheme == "https" {
+			port = "443"
+		}
+		proxyAddr = net.JoinHostPort(d.proxyURL.Hostname(), port)
+	}
+
+	conn, err := d.dialer.DialContext(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, fmt.Errorf("http proxy: failed to connect to proxy: %w", err)
+	}
+
+	if d.proxyURL.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: d.proxyURL.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("http proxy: TLS handshake failed: %w", err)
+		}
+		conn = tlsConn
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if d.proxyURL.User != nil {
+		password, _ := d.proxyURL.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(d.proxyURL.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("http proxy: failed to send CONNECT: %w", err)
+	}
+
+	// После ответа прокси первым говорит клиент (TLS рукопожатие),
+	// поэтому буферизованное чтение не заберет данные туннеля
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("http proxy: failed to read CONNECT response: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("http proxy: CONNECT failed: %s", resp.Status)
+	}
+
+	return conn, nil
+}
+
+// socks4Dialer подключается через SOCKS4/SOCKS4a прокси.
+// SOCKS4 не поддерживает пароль, передается только идентификатор пользователя.
+type socks4Dialer struct {
+	proxyAddr     string
+	userID        string
+	resolveRemote bool
+	dialer        net.Dialer
+}
+
+const (
+	socks4Version        = 0x04
+	socks4CmdConnect     = 0x01
+	socks4RequestGranted = 0x5a
+)
+
+// DialContext устанавливает соединение с addr через прокси
+func (d *socks4Dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if network != "tcp" && network != "tcp4" {
+		return nil, fmt.Errorf("socks4: unsupported network %q", network)
+	}
+
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port <= 0 || port > 65535 {
+		return nil, fmt.Errorf("socks4: invalid port %q", portStr)
+	}
+
+	request := []byte{socks4Version, socks4CmdConnect, 0, 0}
+	binary.BigEndian.PutUint16(request[2:], uint16(port))
+
+	var hostname string
+	ip := net.ParseIP(host).To4()
+	if ip == nil {
+		if d.resolveRemote {
+			// SOCKS4a: адрес 0.0.0.x, имя хоста передается после идентификатора
+			ip = net.IPv4(0, 0, 0, 1).To4()
+			hostname = host
+		} else {
+			ip, err = resolveIPv4(ctx, host)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	request = append(request, ip...)
+	request = append(request, d.userID...)
+	request = append(request, 0)
+	if hostname != "" {
+		request = append(request, hostname...)
+		request = append(request, 0)
+	}
+
+	conn, err := d.dialer.DialContext(ctx, "tcp", d.proxyAddr)
+	if err != nil {
+		return nil, fmt.Errorf("socks4: failed to connect to proxy: %w", err)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	if _, err := conn.Write(request); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("socks4: failed to send request: %w", err)
+	}
+
+	reply := make([]byte, 8)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("socks4: failed to read reply: %w", err)
+	}
+	if reply[1] != socks4RequestGranted {
+		conn.Close()
+		return nil, fmt.Errorf("socks4: request rejected with code 0x%02x", reply[1])
+	}
+
+	return conn, nil
+}
+
+// Dial устанавливает соединение с addr через прокси
+func (d *socks4Dialer) Dial(network, addr string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, addr)
+}
+
+// resolveIPv4 находит IPv4 адрес хоста, SOCKS4 не поддерживает IPv6
+func resolveIPv4(ctx context.Context, host string) (net.IP, error) {
+	addrs, err := net.DefaultResolver.LookupIP(ctx, "ip4", host)
+	if err != nil {
+		return nil, fmt.Errorf("socks4: failed to resolve %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if ip := addr.To4(); ip != nil {
+			return ip, nil
+		}
+	}
+	return nil, fmt.Errorf("socks4: no IPv4 address for %s", host)
+}
@@ -0,0 +1,373 @@
+package kwork
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rtexty/gokwork/pkg/kwork/errors"
+	"golang.org/x/net/proxy"
+)
+
+// ProxyRotation режим выбора прокси из пула
+type ProxyRotation int
+
+const (
+	// ProxyPerRequest каждый запрос идет через следующий здоровый прокси
+	ProxyPerRequest ProxyRotation = iota
+	// ProxyPerSession клиент получает прокси при первом запросе и использует
+	// его, пока прокси не будет исключен из пула
+	ProxyPerSession
+	// ProxySticky аккаунт всегда закрепляется за одним и тем же прокси
+	// (по хэшу логина), чтобы выходной IP не менялся между перезапусками
+	ProxySticky
+)
+
+const (
+	defaultHealthCheckInterval = time.Minute
+	defaultHealthCheckTimeout  = 10 * time.Second
+	defaultHealthCheckAddr     = "api.kwork.ru:443"
+	defaultProxyMaxFailures    = 3
+
+	// proxyRetryInterval как часто без фоновых проверок исключенный прокси
+	// снова пробуется рабочим запросом
+	proxyRetryInterval = time.Minute
+)
+
+// ProxyPoolConfig конфигурация пула прокси
+type ProxyPoolConfig struct {
+	// Proxies адреса прокси в формате Config.ProxyURL
+	Proxies []string
+	// Username и Password используются для прокси, в URL которых не указан логин
+	Username string
+	Password string
+	// Rotation режим выбора прокси
+	Rotation ProxyRotation
+	// HealthCheckInterval период проверки прокси, по умолчанию 1 минута.
+	// Отрицательное значение отключает проверки: тогда исключенный прокси не чаще
+	// раза в минуту получает рабочий запрос, и успешный запрос возвращает его в пул.
+	HealthCheckInterval time.Duration
+	// HealthCheckTimeout таймаут одной проверки, по умолчанию 10 секунд
+	HealthCheckTimeout time.Duration
+	// HealthCheckAddr адрес, к которому проверяется подключение через прокси
+	HealthCheckAddr string
+	// MaxFailures число ошибок подряд, после которого прокси исключается из пула
+	MaxFailures int
+}
+
+// proxyEntry один прокси из пула
+type proxyEntry struct {
+	url       *url.URL
+	transport *http.Transport
+	wsDialer  *websocket.Dialer
+	dialer    proxy.ContextDialer
+
+	failures  int
+	ejected   bool
+	ejectedAt time.Time
+}
+
+// ProxyPool пул прокси с ротацией, проверкой здоровья и закреплением за аккаунтами
+type ProxyPool struct {
+	cfg     ProxyPoolConfig
+	entries []*proxyEntry
+
+	mu       sync.Mutex
+	next     int
+	sessions map[string]*proxyEntry
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewProxyPool создает пул прокси и запускает фоновые проверки здоровья
+func NewProxyPool(cfg ProxyPoolConfig) (*ProxyPool, error) {
+	if len(cfg.Proxies) == 0 {
+		return nil, errors.NewKworkError("proxy pool is empty")
+	}
+	if cfg.HealthCheckInterval == 0 {
+		cfg.HealthCheckInterval = defaultHealthCheckInterval
+	}
+	if cfg.HealthCheckTimeout <= 0 {
+		cfg.HealthCheckTimeout = defaultHealthCheckTimeout
+	}
+	if cfg.HealthCheckAddr == "" {
+		cfg.HealthCheckAddr = defaultHealthCheckAddr
+	}
+	if cfg.MaxFailures <= 0 {
+		cfg.MaxFailures = defaultProxyMaxFailures
+	}
+
+	pool := &ProxyPool{
+		cfg:      cfg,
+		sessions: make(map[string]*proxyEntry),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	for _, raw := range cfg.Proxies {
+		proxyURL, err := parseProxyURL(raw, cfg.Username, cfg.Password)
+		if err != nil {
+			return nil, err
+		}
+
+		transport, wsDialer, err := newTransports(proxyURL)
+		if err != nil {
+			return nil, err
+		}
+
+		dialer, err := newContextDialer(proxyURL)
+		if err != nil {
+			return nil, err
+		}
+
+		pool.entries = append(pool.entries, &proxyEntry{
+			url:       proxyURL,
+			transport: transport,
+			wsDialer:  wsDialer,
+			dialer:    dialer,
+		})
+	}
+
+	if cfg.HealthCheckInterval > 0 {
+		go pool.healthCheckLoop()
+	} else {
+		close(pool.done)
+	}
+
+	return pool, nil
+}
+
+// Close останавливает проверки здоровья и закрывает простаивающие соединения
+func (p *ProxyPool) Close() {
+	select {
+	case <-p.stop:
+	default:
+		close(p.stop)
+	}
+	<-p.done
+
+	for _, entry := range p.entries {
+		entry.transport.CloseIdleConnections()
+	}
+}
+
+// Healthy возвращает адреса прокси, которые сейчас не исключены из пула
+func (p *ProxyPool) Healthy() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var healthy []string
+	for _, entry := range p.entries {
+		if !entry.ejected {
+			healthy = append(healthy, entry.url.Redacted())
+		}
+	}
+	return healthy
+}
+
+// pick выбирает прокси для аккаунта key согласно режиму ротации
+func (p *ProxyPool) pick(key string) (*proxyEntry, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch p.cfg.Rotation {
+	case ProxySticky:
+		h := fnv.New32a()
+		h.Write([]byte(key))
+		start := int(h.Sum32() % uint32(len(p.entries)))
+		// Если закрепленный прокси исключен, временно берем следующий здоровый,
+		// а после восстановления аккаунт вернется на свой прокси
+		for i := 0; i < len(p.entries); i++ {
+			entry := p.entries[(start+i)%len(p.entries)]
+			if p.usable(entry) {
+				return entry, nil
+			}
+		}
+
+	case ProxyPerSession:
+		if entry, ok := p.sessions[key]; ok && !entry.ejected {
+			return entry, nil
+		}
+		entry := p.nextHealthy()
+		if entry != nil {
+			p.sessions[key] = entry
+			return entry, nil
+		}
+
+	default:
+		if entry := p.nextHealthy(); entry != nil {
+			return entry, nil
+		}
+	}
+
+	return nil, errors.NewKworkError("no healthy proxies in pool")
+}
+
+// nextHealthy возвращает следующий здоровый прокси по кругу
+func (p *ProxyPool) nextHealthy() *proxyEntry {
+	for i := 0; i < len(p.entries); i++ {
+		entry := p.entries[p.next%len(p.entries)]
+		p.next++
+		if p.usable(entry) {
+			return entry
+		}
+	}
+	return nil
+}
+
+// usable проверяет, можно ли выдать прокси, вызывается под p.mu.
+// Без фоновых проверок исключенный прокси иначе никогда бы не вернулся в пул,
+// поэтому раз в proxyRetryInterval он выдается для пробного запроса.
+func (p *ProxyPool) usable(entry *proxyEntry) bool {
+	if !entry.ejected {
+		return true
+	}
+	if p.cfg.HealthCheckInterval > 0 || time.Since(entry.ejectedAt) < proxyRetryInterval {
+		return false
+	}
+	entry.ejectedAt = time.Now()
+	return true
+}
+
+// reportFailure учитывает ошибку прокси и исключает его после MaxFailures ошибок подряд
+func (p *ProxyPool) reportFailure(entry *proxyEntry, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entry.failures++
+	if !entry.ejected && entry.failures >= p.cfg.MaxFailures {
+		entry.ejected = true
+		entry.ejectedAt = time.Now()
+		log.Printf("Proxy %s ejected from pool after %d failures: %v", entry.url.Redacted(), entry.failures, err)
+	}
+}
+
+// reportSuccess сбрасывает счетчик ошибок и возвращает прокси в пул
+func (p *ProxyPool) reportSuccess(entry *proxyEntry) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if entry.ejected {
+		log.Printf("Proxy %s is healthy again", entry.url.Redacted())
+	}
+	entry.failures = 0
+	entry.ejected = false
+}
+
+func (p *ProxyPool) healthCheckLoop() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.cfg.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.checkAll()
+		}
+	}
+}
+
+// checkAll проверяет подключение через каждый прокси, включая исключенные
+func (p *ProxyPool) checkAll() {
+	var wg sync.WaitGroup
+	for _, entry := range p.entries {
+		wg.Add(1)
+		go func(entry *proxyEntry) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(), p.cfg.HealthCheckTimeout)
+			defer cancel()
+
+			conn, err := entry.dialer.DialContext(ctx, "tcp", p.cfg.HealthCheckAddr)
+			if err != nil {
+				p.reportFailure(entry, err)
+				return
+			}
+			conn.Close()
+			p.reportSuccess(entry)
+		}(entry)
+	}
+	wg.Wait()
+}
+
+// websocketDialer возвращает dialer для WebSocket с учетом ротации и функцию,
+// которой сообщается результат подключения через выбранный прокси
+func (p *ProxyPool) websocketDialer(key string) (*websocket.Dialer, func(error), error) {
+	entry, err := p.pick(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	report := func(err error) {
+		switch {
+		case err == nil:
+			p.reportSuccess(entry)
+		// Ответ сервера без перехода на WebSocket пришел через прокси,
+		// значит сам прокси работает
+		case stderrors.Is(err, websocket.ErrBadHandshake):
+			p.reportSuccess(entry)
+		default:
+			p.reportFailure(entry, err)
+		}
+	}
+	return entry.wsDialer, report, nil
+}
+
+// poolTransport направляет HTTP запросы клиента через прокси из пула
+type poolTransport struct {
+	pool *ProxyPool
+	key  string
+}
+
+// RoundTrip выполняет запрос через выбранный прокси и учитывает его ошибки
+func (t *poolTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	entry, err := t.pool.pick(t.key)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := entry.transport.RoundTrip(req)
+	if err != nil {
+		if req.Context().Err() == nil {
+			t.pool.reportFailure(entry, err)
+		}
+		return nil, err
+	}
+
+	t.pool.reportSuccess(entry)
+	return resp, nil
+}
+
+// CloseIdleConnections закрывает простаивающие соединения всех прокси пула
+func (t *poolTransport) CloseIdleConnections() {
+	for _, entry := range t.pool.entries {
+		entry.transport.CloseIdleConnections()
+	}
+}
+
+// parseProxyURL разбирает адрес прокси и подставляет логин и пароль,
+// если они не указаны в самом URL
+func parseProxyURL(raw, username, password string) (*url.URL, error) {
+	proxyURL, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	if proxyURL.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q: missing host", raw)
+	}
+	if proxyURL.User == nil && username != "" {
+		proxyURL.User = url.UserPassword(username, password)
+	}
+	return proxyURL, nil
+}
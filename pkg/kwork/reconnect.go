@@ -0,0 +1,83 @@
+package kwork
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// ReconnectPolicy политика переподключения к WebSocket
+type ReconnectPolicy struct {
+	// InitialDelay задержка перед первой попыткой переподключения
+	InitialDelay time.Duration
+	// MaxDelay максимальная задержка между попытками
+	MaxDelay time.Duration
+	// Multiplier множитель задержки для каждой следующей попытки
+	Multiplier float64
+	// Jitter доля случайного отклонения задержки, от 0 до 1
+	Jitter float64
+	// MaxAttempts максимальное число попыток подряд, 0 — без ограничений
+	MaxAttempts int
+	// StableAfter время, которое соединение должно продержаться, чтобы
+	// счетчик попыток начался заново. По умолчанию 1 минута.
+	StableAfter time.Duration
+}
+
+// DefaultReconnectPolicy возвращает политику переподключения по умолчанию
+func DefaultReconnectPolicy() ReconnectPolicy {
+	return ReconnectPolicy{
+		InitialDelay: time.Second,
+		MaxDelay:     2 * time.Minute,
+		Multiplier:   2,
+		Jitter:       0.2,
+		StableAfter:  time.Minute,
+	}
+}
+
+// withDefaults заполняет незаданные параметры значениями по умолчанию
+func (p ReconnectPolicy) withDefaults() ReconnectPolicy {
+	defaults := DefaultReconnectPolicy()
+	if p.InitialDelay <= 0 {
+		p.InitialDelay = defaults.InitialDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = defaults.MaxDelay
+	}
+	if p.MaxDelay < p.InitialDelay {
+		p.MaxDelay = p.InitialDelay
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = defaults.Multiplier
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		p.Jitter = defaults.Jitter
+	}
+	if p.StableAfter <= 0 {
+		p.StableAfter = defaults.StableAfter
+	}
+	return p
+}
+
+// Delay вычисляет задержку перед попыткой с номером attempt (начиная с 1)
+func (p ReconnectPolicy) Delay(attempt int) time.Duration {
+	p = p.withDefaults()
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	delay := float64(p.InitialDelay) * math.Pow(p.Multiplier, float64(attempt-1))
+	if delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (rand.Float64()*2 - 1)
+	}
+
+	return time.Duration(delay)
+}
+
+// exhausted проверяет, исчерпаны ли попытки переподключения
+func (p ReconnectPolicy) exhausted(attempt int) bool {
+	return p.MaxAttempts > 0 && attempt > p.MaxAttempts
+}
@@ -0,0 +1,151 @@
+package kwork
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rtexty/gokwork/pkg/kwork/internal/wait"
+	"github.com/rtexty/gokwork/pkg/kwork/types"
+)
+
+// maxFrameSize максимальный размер строки с кадром при чтении записи
+const maxFrameSize = 4 * 1024 * 1024
+
+// FrameRecord запись одного кадра канала уведомлений
+type FrameRecord struct {
+	Time  time.Time `json:"time"`
+	Frame string    `json:"frame"`
+}
+
+// FrameRecorder записывает кадры WebSocket в JSONL: одна запись на строку
+type FrameRecorder struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+}
+
+// NewFrameRecorder создает запись кадров в w
+func NewFrameRecorder(w io.Writer) *FrameRecorder {
+	return &FrameRecorder{w: w}
+}
+
+// OpenFrameRecorder открывает файл записи кадров, новые кадры дописываются в конец
+func OpenFrameRecorder(path string) (*FrameRecorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &FrameRecorder{w: file, closer: file}, nil
+}
+
+// Record записывает кадр с текущим временем
+func (r *FrameRecorder) Record(frame []byte) error {
+	line, err := json.Marshal(FrameRecord{Time: time.Now(), Frame: string(frame)})
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, err = r.w.Write(line)
+	return err
+}
+
+// Close закрывает файл записи, если он был открыт через OpenFrameRecorder
+func (r *FrameRecorder) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
+
+// ReadFrames читает все записанные кадры
+func ReadFrames(r io.Reader) ([]FrameRecord, error) {
+	var records []FrameRecord
+	err := scanFrames(r, func(record FrameRecord) error {
+		records = append(records, record)
+		return nil
+	})
+	return records, err
+}
+
+// ParseFrame разбирает записанный кадр так же, как кадр живого соединения
+func ParseFrame(frame []byte) (types.Event, error) {
+	return parseEvent(frame)
+}
+
+// scanFrames построчно читает записи кадров
+func scanFrames(r io.Reader, fn func(FrameRecord) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxFrameSize)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var record FrameRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return fmt.Errorf("invalid frame record on line %d: %w", line, err)
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// replay воспроизводит кадры из Config.ReplayFile
+func (c *Client) replay(ctx context.Context, handle func(types.Event)) error {
+	file, err := os.Open(c.replayFile)
+	if err != nil {
+		return fmt.Errorf("failed to open replay file: %w", err)
+	}
+	defer file.Close()
+
+	log.Printf("Replaying WebSocket frames from %s", c.replayFile)
+	return c.replayFrames(ctx, file, c.replaySpeed, handle)
+}
+
+// replayFrames воспроизводит записанные кадры вместо живого соединения.
+// speed задает ускорение: 1 — исходный темп, 10 — в 10 раз быстрее,
+// 0 — без пауз между кадрами.
+func (c *Client) replayFrames(ctx context.Context, r io.Reader, speed float64, handle func(types.Event)) error {
+	var prev time.Time
+
+	return scanFrames(r, func(record FrameRecord) error {
+		if speed > 0 && !prev.IsZero() && record.Time.After(prev) {
+			delay := time.Duration(float64(record.Time.Sub(prev)) / speed)
+			if !wait.Sleep(ctx, delay) {
+				return ctx.Err()
+			}
+		}
+		prev = record.Time
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		event, err := parseEvent([]byte(record.Frame))
+		if err != nil {
+			log.Printf("Failed to parse replayed frame: %v", err)
+			return nil
+		}
+
+		c.updatePresence(event)
+		handle(event)
+		return nil
+	})
+}
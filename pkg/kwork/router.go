@@ -0,0 +1,161 @@
+package kwork
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/rtexty/gokwork/pkg/kwork/types"
+)
+
+// Filter условие, при котором обработчик получает сообщение
+type Filter func(ctx context.Context, b *Bot, msg *types.Message) bool
+
+// Any пропускает все сообщения
+func Any() Filter {
+	return func(ctx context.Context, b *Bot, msg *types.Message) bool {
+		return true
+	}
+}
+
+// Exact пропускает сообщения, текст которых совпадает с text без учета регистра
+func Exact(text string) Filter {
+	return func(ctx context.Context, b *Bot, msg *types.Message) bool {
+		return strings.EqualFold(strings.TrimSpace(msg.Text), text)
+	}
+}
+
+// Contains пропускает сообщения, содержащие подстроку без учета регистра
+func Contains(substr string) Filter {
+	lower := strings.ToLower(substr)
+	return func(ctx context.Context, b *Bot, msg *types.Message) bool {
+		return strings.Contains(strings.ToLower(msg.Text), lower)
+	}
+}
+
+// ContainsWord пропускает сообщения, в которых есть слово word без учета
+// регистра и знаков препинания по краям слова
+func ContainsWord(word string) Filter {
+	return func(ctx context.Context, b *Bot, msg *types.Message) bool {
+		return containsWord(msg.Text, word)
+	}
+}
+
+// Regex пропускает сообщения, текст которых соответствует регулярному выражению.
+// Паникует, если выражение некорректно.
+func Regex(pattern string) Filter {
+	re := regexp.MustCompile(pattern)
+	return func(ctx context.Context, b *Bot, msg *types.Message) bool {
+		return re.MatchString(msg.Text)
+	}
+}
+
+// FromUser пропускает сообщения от указанных пользователей
+func FromUser(userIDs ...int) Filter {
+	allowed := make(map[int]bool, len(userIDs))
+	for _, id := range userIDs {
+		allowed[id] = true
+	}
+	return func(ctx context.Context, b *Bot, msg *types.Message) bool {
+		return allowed[msg.FromID]
+	}
+}
+
+// FirstMessage пропускает первое сообщение нового собеседника.
+// Собеседники запоминаются в Config.ContactStore, поэтому фильтр срабатывает
+// для каждого клиента один раз.
+func FirstMessage() Filter {
+	return func(ctx context.Context, b *Bot, msg *types.Message) bool {
+		return b.isFirstMessage(ctx, msg)
+	}
+}
+
+// HasAttachment пропускает сообщения с прикрепленными файлами
+func HasAttachment() Filter {
+	return func(ctx context.Context, b *Bot, msg *types.Message) bool {
+		return len(msg.Files) > 0
+	}
+}
+
+// And пропускает сообщение, если его пропускают все фильтры
+func And(filters ...Filter) Filter {
+	return func(ctx context.Context, b *Bot, msg *types.Message) bool {
+		for _, filter := range filters {
+			if !filter(ctx, b, msg) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or пропускает сообщение, если его пропускает хотя бы один фильтр
+func Or(filters ...Filter) Filter {
+	return func(ctx context.Context, b *Bot, msg *types.Message) bool {
+		for _, filter := range filters {
+			if filter(ctx, b, msg) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not инвертирует фильтр
+func Not(filter Filter) Filter {
+	return func(ctx context.Context, b *Bot, msg *types.Message) bool {
+		return !filter(ctx, b, msg)
+	}
+}
+
+// Route обработчик, зарегистрированный через Bot.Handle
+type Route struct {
+	filter   Filter
+	handler  HandlerFunc
+	priority int
+	group    *Group
+
+	// wrapped обработчик вместе с middleware, собирается при запуске бота
+	wrapped HandlerFunc
+}
+
+// Priority задает приоритет обработчика. Обработчики с большим приоритетом
+// получают сообщение раньше, при равном приоритете — в порядке регистрации.
+func (r *Route) Priority(priority int) *Route {
+	r.priority = priority
+	return r
+}
+
+// matches проверяет, должен ли обработчик получить сообщение
+func (r *Route) matches(ctx context.Context, b *Bot, msg *types.Message) bool {
+	return r.filter == nil || r.filter(ctx, b, msg)
+}
+
+// buildRoutes оборачивает обработчики в middleware и сортирует их
+// в порядке убывания приоритета
+func (b *Bot) buildRoutes() []*Route {
+	sorted := make([]*Route, len(b.routes))
+	copy(sorted, b.routes)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].priority > sorted[j].priority
+	})
+
+	for _, route := range sorted {
+		middleware := append(append([]Middleware{}, b.middleware...), route.group.chain()...)
+		route.wrapped = chain(route.handler, middleware)
+	}
+	return sorted
+}
+
+// containsWord проверяет, есть ли в тексте слово word
+func containsWord(text, word string) bool {
+	lowerWord := strings.ToLower(word)
+	for _, w := range strings.Fields(strings.ToLower(text)) {
+		// Убираем знаки препинания
+		if strings.Trim(w, "...!.?-") == lowerWord {
+			return true
+		}
+	}
+	return false
+}
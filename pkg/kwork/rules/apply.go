@@ -0,0 +1,247 @@
+package rules
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rtexty/gokwork/pkg/kwork"
+	"github.com/rtexty/gokwork/pkg/kwork/errors"
+	"github.com/rtexty/gokwork/pkg/kwork/internal/wait"
+	"github.com/rtexty/gokwork/pkg/kwork/types"
+)
+
+// TemplatesDir возвращает путь к каталогу шаблонов или пустую строку,
+// если правила не используют шаблоны
+func (c *Config) TemplatesDir() string {
+	if c.Templates == "" || filepath.IsAbs(c.Templates) {
+		return c.Templates
+	}
+	return filepath.Join(c.dir, c.Templates)
+}
+
+// CheckTemplates проверяет, что все шаблоны, на которые ссылаются правила, загружены
+func (c *Config) CheckTemplates(templates *kwork.Templates) error {
+	var errs Errors
+	for i, rule := range c.Rules {
+		if rule.Template == "" {
+			continue
+		}
+		if templates == nil || !templates.Has(rule.Template) {
+			errs = append(errs, c.errorAt(fmt.Sprintf("template %q not found", rule.Template), "rules", strconv.Itoa(i), "template"))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Apply регистрирует обработчики правил в боте. templates нужны, если правила
+// ссылаются на шаблоны из файлов (см. TemplatesDir).
+func (c *Config) Apply(ctx context.Context, bot *kwork.Bot, templates *kwork.Templates) error {
+	if err := c.CheckTemplates(templates); err != nil {
+		return err
+	}
+
+	var schedule *kwork.Schedule
+	if c.Schedule != nil {
+		var err error
+		if schedule, err = c.buildSchedule(ctx, bot); err != nil {
+			return fmt.Errorf("failed to build schedule: %w", err)
+		}
+	}
+
+	for i := range c.Rules {
+		rule := &c.Rules[i]
+		bot.Handle(rule.filter(schedule), rule.handler(bot, templates)).Priority(rule.Priority)
+	}
+
+	return nil
+}
+
+// buildSchedule создает рабочий график, недостающие настройки берутся из профиля аккаунта
+func (c *Config) buildSchedule(ctx context.Context, bot *kwork.Bot) (*kwork.Schedule, error) {
+	spec := c.Schedule
+
+	hours, _ := parseHours(spec.Hours)
+	cfg := kwork.ScheduleConfig{
+		Hours:    kwork.WorkingHours{Start: hours[0], End: hours[1]},
+		Holidays: spec.Holidays,
+	}
+	if spec.WeekendHours != "" {
+		weekend, _ := parseHours(spec.WeekendHours)
+		cfg.WeekendHours = &kwork.WorkingHours{Start: weekend[0], End: weekend[1]}
+	}
+	if spec.Timezone != "" {
+		loc, err := time.LoadLocation(spec.Timezone)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Location = loc
+	}
+
+	if spec.Weekends != nil && cfg.Location != nil {
+		cfg.Weekends = *spec.Weekends
+		return kwork.NewSchedule(cfg)
+	}
+
+	actor, err := bot.GetMe(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.Timezones = spec.Timezones
+	schedule, err := kwork.NewScheduleForActor(actor, cfg)
+	if err != nil || spec.Weekends == nil {
+		return schedule, err
+	}
+
+	// Явная настройка выходных важнее профиля
+	cfg.Weekends = *spec.Weekends
+	cfg.Location = schedule.Location()
+	return kwork.NewSchedule(cfg)
+}
+
+// filter собирает фильтр из условий правила
+func (r *Rule) filter(schedule *kwork.Schedule) kwork.Filter {
+	var filters []kwork.Filter
+
+	if r.Match.FirstMessage {
+		filters = append(filters, kwork.FirstMessage())
+	}
+	if r.Match.Exact != "" {
+		filters = append(filters, kwork.Exact(r.Match.Exact))
+	}
+	if r.Match.Contains != "" {
+		filters = append(filters, kwork.Contains(r.Match.Contains))
+	}
+	if r.Match.Word != "" {
+		filters = append(filters, kwork.ContainsWord(r.Match.Word))
+	}
+	if r.regex != nil {
+		re := r.regex
+		filters = append(filters, func(ctx context.Context, b *kwork.Bot, msg *types.Message) bool {
+			return re.MatchString(msg.Text)
+		})
+	}
+	switch r.Match.Schedule {
+	case ScheduleWorking:
+		filters = append(filters, schedule.WorkingHours())
+	case ScheduleOff:
+		filters = append(filters, schedule.OffHours())
+	}
+
+	if len(filters) == 0 {
+		return nil
+	}
+	return kwork.And(filters...)
+}
+
+// handler создает обработчик, отвечающий по правилу
+func (r *Rule) handler(bot *kwork.Bot, templates *kwork.Templates) kwork.HandlerFunc {
+	cooldown := newCooldown(r.Cooldown)
+
+	return func(ctx context.Context, msg *types.Message) error {
+		if !cooldown.allow(msg.FromID) {
+			return r.result(nil)
+		}
+
+		text, err := r.render(ctx, bot, templates, msg)
+		if err != nil {
+			return err
+		}
+
+		if !wait.Sleep(ctx, r.Delay) {
+			return ctx.Err()
+		}
+
+		if r.Typing {
+			err = msg.AnswerSimulation(ctx, text)
+		} else {
+			err = msg.FastAnswer(ctx, text)
+		}
+		if err == nil {
+			// Неудачный ответ не должен откладывать следующую попытку
+			cooldown.record(msg.FromID)
+		}
+		return r.result(err)
+	}
+}
+
+// result останавливает передачу сообщения следующим правилам, если это задано в правиле
+func (r *Rule) result(err error) error {
+	if err == nil && r.Stop {
+		return errors.ErrStopPropagation
+	}
+	return err
+}
+
+// render заполняет текст ответа
+func (r *Rule) render(ctx context.Context, bot *kwork.Bot, templates *kwork.Templates, msg *types.Message) (string, error) {
+	if r.Template != "" {
+		return bot.RenderReply(ctx, templates, r.Template, msg)
+	}
+
+	data, err := bot.TemplateData(ctx, msg)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := r.reply.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render reply for %s: %w", r.Name, err)
+	}
+	return buf.String(), nil
+}
+
+// cooldown ограничивает частоту ответов одному клиенту
+type cooldown struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	last map[int]time.Time
+}
+
+func newCooldown(interval time.Duration) *cooldown {
+	return &cooldown{interval: interval, last: make(map[int]time.Time)}
+}
+
+// allow проверяет, прошел ли интервал с последнего ответа клиенту.
+// Сообщения одного клиента обрабатываются по очереди, поэтому между
+// allow и record ответ этому клиенту никто другой не отправит.
+func (c *cooldown) allow(userID int) bool {
+	if c.interval <= 0 {
+		return true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	last, ok := c.last[userID]
+	return !ok || time.Since(last) >= c.interval
+}
+
+// record отмечает отправленный клиенту ответ
+func (c *cooldown) record(userID int) {
+	if c.interval <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	// Забываем клиентов, для которых интервал уже прошел
+	for id, last := range c.last {
+		if now.Sub(last) >= c.interval {
+			delete(c.last, id)
+		}
+	}
+
+	c.last[userID] = now
+}
@@ -0,0 +1,332 @@
+// Package rules строит обработчики бота из декларативного файла правил
+// в формате YAML или JSON
+package rules
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Значения условия schedule
+const (
+	ScheduleWorking = "working"
+	ScheduleOff     = "off"
+)
+
+// Config содержимое файла правил
+type Config struct {
+	// Templates каталог шаблонов ответов относительно файла правил
+	Templates string `yaml:"templates" json:"templates"`
+	// Schedule рабочий график для условия schedule
+	Schedule *ScheduleSpec `yaml:"schedule" json:"schedule"`
+	// Rules правила в порядке убывания важности
+	Rules []Rule `yaml:"rules" json:"rules"`
+
+	// path и dir файл правил и его каталог
+	path string
+	dir  string
+	// node разобранный документ для поиска строк при ошибках
+	node *yaml.Node
+}
+
+// ScheduleSpec рабочий график
+type ScheduleSpec struct {
+	// Hours рабочие часы в будни в формате 09:00-18:00
+	Hours string `yaml:"hours" json:"hours"`
+	// WeekendHours рабочие часы в выходные
+	WeekendHours string `yaml:"weekend_hours" json:"weekend_hours"`
+	// Weekends работа в выходные. Если не задано, берется из профиля аккаунта.
+	Weekends *bool `yaml:"weekends" json:"weekends"`
+	// Holidays нерабочие дни в формате 2006-01-02
+	Holidays []string `yaml:"holidays" json:"holidays"`
+	// Timezone часовой пояс IANA. Если не задан, определяется по профилю аккаунта
+	// через Timezones.
+	Timezone string `yaml:"timezone" json:"timezone"`
+	// Timezones соответствие TimezoneID Kwork именам IANA
+	Timezones map[int]string `yaml:"timezones" json:"timezones"`
+}
+
+// Rule правило автоответа
+type Rule struct {
+	// Name имя правила для логов
+	Name string `yaml:"name" json:"name"`
+	// Match условия, все заданные условия должны выполняться
+	Match Match `yaml:"match" json:"match"`
+	// Reply текст ответа в синтаксисе text/template
+	Reply string `yaml:"reply" json:"reply"`
+	// Template имя шаблона ответа из каталога templates
+	Template string `yaml:"template" json:"template"`
+	// Typing показывать набор текста перед ответом
+	Typing bool `yaml:"typing" json:"typing"`
+	// Delay пауза перед ответом, например 3s
+	Delay time.Duration `yaml:"delay" json:"delay"`
+	// Cooldown минимальный интервал между ответами одному клиенту по этому правилу
+	Cooldown time.Duration `yaml:"cooldown" json:"cooldown"`
+	// Priority приоритет правила, правила с большим приоритетом проверяются раньше
+	Priority int `yaml:"priority" json:"priority"`
+	// Stop не передавать сообщение следующим правилам после ответа
+	Stop bool `yaml:"stop" json:"stop"`
+
+	regex *regexp.Regexp
+	reply *template.Template
+}
+
+// Match условия правила
+type Match struct {
+	// Exact точное совпадение текста без учета регистра
+	Exact string `yaml:"exact" json:"exact"`
+	// Contains подстрока без учета регистра
+	Contains string `yaml:"contains" json:"contains"`
+	// Word отдельное слово в тексте
+	Word string `yaml:"word" json:"word"`
+	// Regex регулярное выражение
+	Regex string `yaml:"regex" json:"regex"`
+	// FirstMessage только первое сообщение нового клиента
+	FirstMessage bool `yaml:"first_message" json:"first_message"`
+	// Schedule working — в рабочее время, off — в нерабочее
+	Schedule string `yaml:"schedule" json:"schedule"`
+}
+
+// Error ошибка в файле правил с номером строки
+type Error struct {
+	File    string
+	Line    int
+	Message string
+}
+
+func (e Error) Error() string {
+	location := e.File
+	if e.Line != 0 {
+		location = fmt.Sprintf("%s:%d", e.File, e.Line)
+		if e.File == "" {
+			location = fmt.Sprintf("line %d", e.Line)
+		}
+	}
+	if location == "" {
+		return e.Message
+	}
+	return location + ": " + e.Message
+}
+
+// Errors все ошибки, найденные при проверке файла правил
+type Errors []Error
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Load читает и проверяет файл правил
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := Parse(data)
+	if errs, ok := err.(Errors); ok {
+		for i := range errs {
+			errs[i].File = path
+		}
+		return nil, errs
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	cfg.path = path
+	cfg.dir = filepath.Dir(path)
+	return cfg, nil
+}
+
+// Parse разбирает и проверяет правила. JSON разбирается как подмножество YAML.
+func Parse(data []byte) (*Config, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	// Пустой файл не ошибка разбора: validate сообщит об отсутствии правил
+	if err := decoder.Decode(&cfg); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	cfg.node = &node
+	if errs := cfg.validate(); len(errs) > 0 {
+		return nil, errs
+	}
+
+	return &cfg, nil
+}
+
+// validate проверяет правила и компилирует выражения и шаблоны
+func (c *Config) validate() Errors {
+	var errs Errors
+
+	if c.Schedule != nil {
+		if _, err := parseHours(c.Schedule.Hours); err != nil {
+			errs = append(errs, c.errorAt(err.Error(), "schedule", "hours"))
+		}
+		if c.Schedule.WeekendHours != "" {
+			if _, err := parseHours(c.Schedule.WeekendHours); err != nil {
+				errs = append(errs, c.errorAt(err.Error(), "schedule", "weekend_hours"))
+			}
+		}
+		for i, day := range c.Schedule.Holidays {
+			if _, err := time.Parse("2006-01-02", day); err != nil {
+				errs = append(errs, c.errorAt(fmt.Sprintf("invalid holiday %q, expected YYYY-MM-DD", day), "schedule", "holidays", strconv.Itoa(i)))
+			}
+		}
+		if c.Schedule.Timezone != "" {
+			if _, err := time.LoadLocation(c.Schedule.Timezone); err != nil {
+				errs = append(errs, c.errorAt(fmt.Sprintf("unknown timezone %q", c.Schedule.Timezone), "schedule", "timezone"))
+			}
+		}
+	}
+
+	if len(c.Rules) == 0 {
+		errs = append(errs, c.errorAt("no rules defined", "rules"))
+	}
+
+	for i := range c.Rules {
+		rule := &c.Rules[i]
+		path := []string{"rules", strconv.Itoa(i)}
+		at := func(message string, field ...string) Error {
+			return c.errorAt(message, append(append([]string{}, path...), field...)...)
+		}
+
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule %d", i+1)
+		}
+
+		switch {
+		case rule.Reply == "" && rule.Template == "":
+			errs = append(errs, at("either reply or template is required"))
+		case rule.Reply != "" && rule.Template != "":
+			errs = append(errs, at("reply and template are mutually exclusive", "template"))
+		case rule.Reply != "":
+			tmpl, err := template.New(rule.Name).Parse(rule.Reply)
+			if err != nil {
+				errs = append(errs, at(fmt.Sprintf("invalid reply template: %v", err), "reply"))
+			}
+			rule.reply = tmpl
+		}
+
+		if rule.Template != "" && c.Templates == "" {
+			errs = append(errs, at("template requires the templates directory", "template"))
+		}
+
+		if rule.Match.Regex != "" {
+			re, err := regexp.Compile(rule.Match.Regex)
+			if err != nil {
+				errs = append(errs, at(fmt.Sprintf("invalid regex: %v", err), "match", "regex"))
+			}
+			rule.regex = re
+		}
+
+		switch rule.Match.Schedule {
+		case "":
+		case ScheduleWorking, ScheduleOff:
+			if c.Schedule == nil {
+				errs = append(errs, at("schedule condition requires the schedule section", "match", "schedule"))
+			}
+		default:
+			errs = append(errs, at(fmt.Sprintf("invalid schedule %q, expected %q or %q", rule.Match.Schedule, ScheduleWorking, ScheduleOff), "match", "schedule"))
+		}
+
+		if rule.Delay < 0 {
+			errs = append(errs, at("delay must not be negative", "delay"))
+		}
+		if rule.Cooldown < 0 {
+			errs = append(errs, at("cooldown must not be negative", "cooldown"))
+		}
+	}
+
+	return errs
+}
+
+// errorAt создает ошибку со строкой узла по пути ключей и индексов.
+// Если узел не найден, используется строка ближайшего найденного родителя.
+func (c *Config) errorAt(message string, path ...string) Error {
+	return Error{File: c.path, Line: lineOf(c.node, path...), Message: message}
+}
+
+// lineOf возвращает строку узла документа по пути
+func lineOf(node *yaml.Node, path ...string) int {
+	if node == nil {
+		return 0
+	}
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+
+	line := node.Line
+	for _, key := range path {
+		var next *yaml.Node
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == key {
+					line = node.Content[i].Line
+					next = node.Content[i+1]
+					break
+				}
+			}
+		case yaml.SequenceNode:
+			if i, err := strconv.Atoi(key); err == nil && i < len(node.Content) {
+				next = node.Content[i]
+				line = next.Line
+			}
+		}
+		if next == nil {
+			return line
+		}
+		node = next
+	}
+
+	return line
+}
+
+// parseHours разбирает интервал рабочих часов 09:00-18:00
+func parseHours(value string) ([2]time.Duration, error) {
+	var hours [2]time.Duration
+
+	parts := strings.Split(value, "-")
+	if len(parts) != 2 {
+		return hours, fmt.Errorf("invalid hours %q, expected HH:MM-HH:MM", value)
+	}
+
+	for i, part := range parts {
+		t, err := time.Parse("15:04", strings.TrimSpace(part))
+		if err != nil {
+			// 24:00 — конец суток
+			if strings.TrimSpace(part) != "24:00" {
+				return hours, fmt.Errorf("invalid hours %q, expected HH:MM-HH:MM", value)
+			}
+			hours[i] = 24 * time.Hour
+			continue
+		}
+		hours[i] = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	}
+
+	if hours[0] >= hours[1] {
+		return hours, fmt.Errorf("invalid hours %q: start must be before end", value)
+	}
+	return hours, nil
+}
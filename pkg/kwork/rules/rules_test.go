@@ -0,0 +1,284 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseValid(t *testing.T) {
+	data := `
+templates: templates
+schedule:
+  hours: "09:00-18:00"
+  holidays: ["2026-12-31"]
+  timezone: Europe/Moscow
+rules:
+  - name: price
+    match:
+      word: цена
+      schedule: working
+    reply: "Здравствуйте, {{.Name}}!"
+    cooldown: 1h
+  - match:
+      regex: "(?i)срочно"
+    template: urgent
+    delay: 3s
+`
+	cfg, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cfg.Rules) != 2 {
+		t.Fatalf("parsed %d rules, want 2", len(cfg.Rules))
+	}
+	if cfg.Rules[0].Cooldown != time.Hour || cfg.Rules[0].reply == nil {
+		t.Errorf("first rule parsed as %+v", cfg.Rules[0])
+	}
+	if cfg.Rules[1].Name != "rule 2" || cfg.Rules[1].regex == nil || cfg.Rules[1].Delay != 3*time.Second {
+		t.Errorf("second rule parsed as %+v", cfg.Rules[1])
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	cfg, err := Parse([]byte(`{"rules": [{"match": {"exact": "привет"}, "reply": "Здравствуйте!", "stop": true}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Rules) != 1 || cfg.Rules[0].Match.Exact != "привет" || !cfg.Rules[0].Stop {
+		t.Errorf("parsed %+v", cfg.Rules)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		line    int
+		message string
+	}{
+		{
+			name:    "empty file",
+			data:    ``,
+			message: "no rules defined",
+		},
+		{
+			name: "no reply",
+			data: `rules:
+  - name: empty
+    match:
+      exact: привет`,
+			line:    2,
+			message: "either reply or template is required",
+		},
+		{
+			name: "reply and template",
+			data: `templates: templates
+rules:
+  - reply: "Привет"
+    template: greeting`,
+			line:    4,
+			message: "reply and template are mutually exclusive",
+		},
+		{
+			name: "template without directory",
+			data: `rules:
+  - template: greeting`,
+			line:    2,
+			message: "template requires the templates directory",
+		},
+		{
+			name: "invalid reply template",
+			data: `rules:
+  - match:
+      exact: привет
+    reply: "{{.Name"`,
+			line:    4,
+			message: "invalid reply template",
+		},
+		{
+			name: "invalid regex",
+			data: `rules:
+  - match:
+      regex: "(["
+    reply: "Привет"`,
+			line:    3,
+			message: "invalid regex",
+		},
+		{
+			name: "schedule without section",
+			data: `rules:
+  - match:
+      schedule: off
+    reply: "Не рабочее время"`,
+			line:    3,
+			message: "schedule condition requires the schedule section",
+		},
+		{
+			name: "invalid schedule value",
+			data: `schedule:
+  hours: "09:00-18:00"
+rules:
+  - match:
+      schedule: night
+    reply: "Привет"`,
+			line:    5,
+			message: `invalid schedule "night"`,
+		},
+		{
+			name: "negative delay",
+			data: `rules:
+  - reply: "Привет"
+    delay: -1s`,
+			line:    3,
+			message: "delay must not be negative",
+		},
+		{
+			name: "negative cooldown",
+			data: `rules:
+  - reply: "Привет"
+    cooldown: -1m`,
+			line:    3,
+			message: "cooldown must not be negative",
+		},
+		{
+			name: "invalid hours",
+			data: `schedule:
+  hours: "18:00-09:00"
+rules:
+  - reply: "Привет"`,
+			line:    2,
+			message: "start must be before end",
+		},
+		{
+			name: "invalid weekend hours",
+			data: `schedule:
+  hours: "09:00-18:00"
+  weekend_hours: "10-14"
+rules:
+  - reply: "Привет"`,
+			line:    3,
+			message: `invalid hours "10-14"`,
+		},
+		{
+			name: "invalid holiday",
+			data: `schedule:
+  hours: "09:00-18:00"
+  holidays:
+    - "2026-12-31"
+    - "31.12.2026"
+rules:
+  - reply: "Привет"`,
+			line:    5,
+			message: `invalid holiday "31.12.2026"`,
+		},
+		{
+			name: "unknown timezone",
+			data: `schedule:
+  hours: "09:00-18:00"
+  timezone: Mars/Olympus
+rules:
+  - reply: "Привет"`,
+			line:    3,
+			message: `unknown timezone "Mars/Olympus"`,
+		},
+		{
+			name: "second rule",
+			data: `rules:
+  - reply: "Привет"
+  - name: broken
+    reply: "Пока"
+    delay: -5s`,
+			line:    5,
+			message: "delay must not be negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data))
+			errs, ok := err.(Errors)
+			if !ok {
+				t.Fatalf("Parse error = %v (%T), want Errors", err, err)
+			}
+			if len(errs) != 1 {
+				t.Fatalf("got %d errors, want 1: %v", len(errs), errs)
+			}
+			if errs[0].Line != tt.line {
+				t.Errorf("error line = %d, want %d (%v)", errs[0].Line, tt.line, errs[0])
+			}
+			if !strings.Contains(errs[0].Message, tt.message) {
+				t.Errorf("error message = %q, want it to contain %q", errs[0].Message, tt.message)
+			}
+		})
+	}
+}
+
+func TestParseCollectsAllErrors(t *testing.T) {
+	_, err := Parse([]byte(`rules:
+  - reply: "Привет"
+    delay: -1s
+  - match:
+      regex: "(["
+    reply: "Пока"`))
+
+	errs, ok := err.(Errors)
+	if !ok || len(errs) != 2 {
+		t.Fatalf("Parse error = %v, want 2 errors", err)
+	}
+	if errs[0].Line != 3 || errs[1].Line != 5 {
+		t.Errorf("error lines = %d, %d, want 3, 5", errs[0].Line, errs[1].Line)
+	}
+	if got, want := errs.Error(), "line 3: delay must not be negative\nline 5: invalid regex"; !strings.HasPrefix(got, want) {
+		t.Errorf("Error() = %q, want prefix %q", got, want)
+	}
+}
+
+func TestParseUnknownField(t *testing.T) {
+	if _, err := Parse([]byte(`rules:
+  - reply: "Привет"
+    replay: "опечатка"`)); err == nil {
+		t.Error("unknown field accepted")
+	}
+}
+
+func TestLoadErrorIncludesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte("rules:\n  - reply: \"Привет\"\n    delay: -1s\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := Load(path)
+	if err == nil {
+		t.Fatal("Load succeeded, want error")
+	}
+	if want := path + ":3: delay must not be negative"; err.Error() != want {
+		t.Errorf("Load error = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestCooldownRecordsOnlyReplies(t *testing.T) {
+	c := newCooldown(time.Hour)
+
+	// Проверка сама по себе не занимает интервал: ответ мог не отправиться
+	if !c.allow(1) || !c.allow(1) {
+		t.Fatal("allow blocked a client without a recorded reply")
+	}
+
+	c.record(1)
+	if c.allow(1) {
+		t.Error("allow passed a client within the cooldown")
+	}
+	if !c.allow(2) {
+		t.Error("cooldown of one client blocked another")
+	}
+
+	disabled := newCooldown(0)
+	disabled.record(1)
+	if !disabled.allow(1) {
+		t.Error("zero cooldown blocked a client")
+	}
+}
@@ -0,0 +1,262 @@
+package kwork
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rtexty/gokwork/pkg/kwork/types"
+)
+
+// defaultScheduleTimezone часовой пояс Kwork, используемый NewSchedule, если пояс не задан
+const defaultScheduleTimezone = "Europe/Moscow"
+
+// holidayLayout формат дат праздников
+const holidayLayout = "2006-01-02"
+
+// WorkingHours рабочее время внутри дня: смещения от полуночи
+type WorkingHours struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// contains проверяет, попадает ли время суток в рабочие часы
+func (h WorkingHours) contains(offset time.Duration) bool {
+	return offset >= h.Start && offset < h.End
+}
+
+// ScheduleConfig конфигурация рабочего графика
+type ScheduleConfig struct {
+	// Hours рабочие часы в будни
+	Hours WorkingHours
+	// WeekendHours рабочие часы в выходные, если в выходные аккаунт работает.
+	// По умолчанию совпадают с Hours.
+	WeekendHours *WorkingHours
+	// Weekends работает ли аккаунт в выходные. NewScheduleForActor берет
+	// значение из Actor.KworksAvailableAtWeekends.
+	Weekends bool
+	// Holidays нерабочие дни в формате 2006-01-02
+	Holidays []string
+	// Location часовой пояс графика. NewSchedule по умолчанию использует
+	// Europe/Moscow, NewScheduleForActor определяет пояс по Actor.TimezoneID
+	// через Timezones и возвращает ошибку, если пояс неизвестен.
+	Location *time.Location
+	// Timezones соответствие идентификаторов часовых поясов Kwork
+	// (Actor.TimezoneID) именам IANA. Встроенной таблицы нет: Kwork
+	// не публикует значения идентификаторов.
+	Timezones map[int]string
+}
+
+// Schedule рабочий график аккаунта
+type Schedule struct {
+	hours        WorkingHours
+	weekendHours WorkingHours
+	weekends     bool
+	holidays     map[string]bool
+	loc          *time.Location
+}
+
+// NewSchedule создает рабочий график
+func NewSchedule(cfg ScheduleConfig) (*Schedule, error) {
+	if cfg.Hours.Start < 0 || cfg.Hours.End > 24*time.Hour || cfg.Hours.Start >= cfg.Hours.End {
+		return nil, fmt.Errorf("invalid working hours %s-%s", cfg.Hours.Start, cfg.Hours.End)
+	}
+
+	weekendHours := cfg.Hours
+	if cfg.WeekendHours != nil {
+		weekendHours = *cfg.WeekendHours
+		if weekendHours.Start < 0 || weekendHours.End > 24*time.Hour || weekendHours.Start >= weekendHours.End {
+			return nil, fmt.Errorf("invalid weekend working hours %s-%s", weekendHours.Start, weekendHours.End)
+		}
+	}
+
+	holidays := make(map[string]bool, len(cfg.Holidays))
+	for _, day := range cfg.Holidays {
+		if _, err := time.Parse(holidayLayout, day); err != nil {
+			return nil, fmt.Errorf("invalid holiday %q: %w", day, err)
+		}
+		holidays[day] = true
+	}
+
+	loc := cfg.Location
+	if loc == nil {
+		var err error
+		if loc, err = time.LoadLocation(defaultScheduleTimezone); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Schedule{
+		hours:        cfg.Hours,
+		weekendHours: weekendHours,
+		weekends:     cfg.Weekends,
+		holidays:     holidays,
+		loc:          loc,
+	}, nil
+}
+
+// NewScheduleForActor создает рабочий график с часовым поясом и режимом
+// работы в выходные из профиля аккаунта. Идентификаторы поясов Kwork
+// не документированы, поэтому пояс аккаунта нужно сопоставить имени IANA
+// в cfg.Timezones или задать cfg.Location явно.
+func NewScheduleForActor(actor *types.Actor, cfg ScheduleConfig) (*Schedule, error) {
+	cfg.Weekends = actor.KworksAvailableAtWeekends
+
+	if cfg.Location == nil {
+		if name, ok := cfg.Timezones[actor.TimezoneID]; ok {
+			loc, err := time.LoadLocation(name)
+			if err != nil {
+				return nil, fmt.Errorf("invalid timezone for id %d: %w", actor.TimezoneID, err)
+			}
+			cfg.Location = loc
+		} else {
+			// Молча подставленный чужой пояс сдвинул бы весь график
+			return nil, fmt.Errorf("unknown timezone id %d: add it to ScheduleConfig.Timezones or set ScheduleConfig.Location", actor.TimezoneID)
+		}
+	}
+
+	return NewSchedule(cfg)
+}
+
+// LoadSchedule создает рабочий график по профилю текущего аккаунта
+func (c *Client) LoadSchedule(ctx context.Context, cfg ScheduleConfig) (*Schedule, error) {
+	actor, err := c.GetMe(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return NewScheduleForActor(actor, cfg)
+}
+
+// Location возвращает часовой пояс графика
+func (s *Schedule) Location() *time.Location {
+	return s.loc
+}
+
+// IsOpen проверяет, рабочее ли время в момент t
+func (s *Schedule) IsOpen(t time.Time) bool {
+	t = t.In(s.loc)
+
+	hours, ok := s.dayHours(t)
+	if !ok {
+		return false
+	}
+
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, s.loc)
+	return hours.contains(t.Sub(midnight))
+}
+
+// NextOpen возвращает начало ближайшего рабочего периода после t.
+// Если в момент t рабочее время, возвращает t. Если в ближайший год
+// рабочих дней нет, возвращает нулевое время.
+func (s *Schedule) NextOpen(t time.Time) time.Time {
+	if s.IsOpen(t) {
+		return t
+	}
+
+	t = t.In(s.loc)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, s.loc)
+
+	// Достаточно с запасом для длинных праздников
+	for i := 0; i < 366; i++ {
+		if hours, ok := s.dayHours(day); ok {
+			start := day.Add(hours.Start)
+			if start.After(t) {
+				return start
+			}
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+
+	return time.Time{}
+}
+
+// dayHours возвращает рабочие часы дня, false — если день нерабочий
+func (s *Schedule) dayHours(t time.Time) (WorkingHours, bool) {
+	if s.holidays[t.Format(holidayLayout)] {
+		return WorkingHours{}, false
+	}
+
+	switch t.Weekday() {
+	case time.Saturday, time.Sunday:
+		return s.weekendHours, s.weekends
+	default:
+		return s.hours, true
+	}
+}
+
+// WorkingHours возвращает фильтр сообщений, полученных в рабочее время
+func (s *Schedule) WorkingHours() Filter {
+	return func(ctx context.Context, b *Bot, msg *types.Message) bool {
+		return s.IsOpen(messageTime(msg))
+	}
+}
+
+// OffHours возвращает фильтр сообщений, полученных в нерабочее время
+func (s *Schedule) OffHours() Filter {
+	return Not(s.WorkingHours())
+}
+
+// messageTime возвращает время отправки сообщения или текущее время, если оно неизвестно
+func messageTime(msg *types.Message) time.Time {
+	if msg.Time == 0 {
+		return time.Now()
+	}
+	return time.Unix(int64(msg.Time), 0)
+}
+
+// AwayResponder отвечает на сообщения в нерабочее время,
+// не больше одного раза каждому клиенту за нерабочий период
+type AwayResponder struct {
+	schedule *Schedule
+	text     string
+
+	mu sync.Mutex
+	// answered начало рабочего периода, до которого клиенту уже ответили;
+	// нулевое время — нерабочий период без известного конца
+	answered map[int]time.Time
+}
+
+// NewAwayResponder создает автоответчик для нерабочего времени
+func NewAwayResponder(schedule *Schedule, text string) *AwayResponder {
+	return &AwayResponder{
+		schedule: schedule,
+		text:     text,
+		answered: make(map[int]time.Time),
+	}
+}
+
+// Handle отвечает клиенту, если в этот нерабочий период ему еще не отвечали.
+// Регистрируется с фильтром Schedule.OffHours.
+func (a *AwayResponder) Handle(ctx context.Context, msg *types.Message) error {
+	at := messageTime(msg)
+	if a.schedule.IsOpen(at) {
+		return nil
+	}
+
+	if !a.claim(msg.FromID, a.schedule.NextOpen(at), at) {
+		return nil
+	}
+
+	return msg.FastAnswer(ctx, a.text)
+}
+
+// claim отмечает ответ клиенту в нерабочем периоде, который заканчивается в opens
+func (a *AwayResponder) claim(userID int, opens, now time.Time) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// Забываем клиентов, чьи нерабочие периоды уже закончились
+	for id, until := range a.answered {
+		if !until.IsZero() && !until.After(now) {
+			delete(a.answered, id)
+		}
+	}
+
+	if until, ok := a.answered[userID]; ok && until.Equal(opens) {
+		return false
+	}
+
+	a.answered[userID] = opens
+	return true
+}
@@ -0,0 +1,322 @@
+package kwork
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// jsonFile читает и атомарно перезаписывает JSON файл для файловых хранилищ
+type jsonFile struct {
+	path string
+}
+
+// load читает файл в v. Отсутствующий файл не считается ошибкой.
+func (f jsonFile) load(v interface{}) error {
+	data, err := os.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, v)
+}
+
+// save записывает v во временный файл и переименовывает его,
+// чтобы при падении процесса не оставить файл поврежденным
+func (f jsonFile) save(v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(f.path)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(f.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+
+	return os.Rename(tmpName, f.path)
+}
+
+// HighWaterMark отметка последнего обработанного входящего сообщения в диалоге
+type HighWaterMark struct {
+	Time      int `json:"time"`
+	MessageID int `json:"message_id"`
+}
+
+// Before проверяет, что сообщение с указанными временем и ID новее отметки
+func (m HighWaterMark) Before(msgTime, messageID int) bool {
+	if msgTime != m.Time {
+		return msgTime > m.Time
+	}
+	return messageID > m.MessageID
+}
+
+// HighWaterStore хранилище отметок последних обработанных сообщений по диалогам.
+// Ключ — ID собеседника, ключ 0 хранит время последней сверки всего аккаунта.
+type HighWaterStore interface {
+	Load(userID int) (HighWaterMark, bool, error)
+	Save(userID int, mark HighWaterMark) error
+}
+
+// MemoryHighWaterStore хранит отметки в памяти процесса
+type MemoryHighWaterStore struct {
+	mu    sync.RWMutex
+	marks map[int]HighWaterMark
+}
+
+// NewMemoryHighWaterStore создает хранилище отметок в памяти
+func NewMemoryHighWaterStore() *MemoryHighWaterStore {
+	return &MemoryHighWaterStore{marks: make(map[int]HighWaterMark)}
+}
+
+// Load возвращает отметку для диалога
+func (s *MemoryHighWaterStore) Load(userID int) (HighWaterMark, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	mark, ok := s.marks[userID]
+	return mark, ok, nil
+}
+
+// Save сохраняет отметку для диалога
+func (s *MemoryHighWaterStore) Save(userID int, mark HighWaterMark) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.marks[userID] = mark
+	return nil
+}
+
+// FileHighWaterStore хранит отметки в JSON файле, чтобы они переживали перезапуск
+type FileHighWaterStore struct {
+	mu    sync.Mutex
+	file  jsonFile
+	marks map[int]HighWaterMark
+}
+
+// NewFileHighWaterStore создает файловое хранилище отметок и загружает сохраненные данные
+func NewFileHighWaterStore(path string) (*FileHighWaterStore, error) {
+	s := &FileHighWaterStore{
+		file:  jsonFile{path: path},
+		marks: make(map[int]HighWaterMark),
+	}
+
+	if err := s.file.load(&s.marks); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Load возвращает отметку для диалога
+func (s *FileHighWaterStore) Load(userID int) (HighWaterMark, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mark, ok := s.marks[userID]
+	return mark, ok, nil
+}
+
+// Save сохраняет отметку для диалога и записывает файл
+func (s *FileHighWaterStore) Save(userID int, mark HighWaterMark) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.marks[userID] = mark
+	return s.file.save(s.marks)
+}
+
+// ConversationState состояние диалога с пользователем
+type ConversationState struct {
+	State     string            `json:"state"`
+	Data      map[string]string `json:"data,omitempty"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// StateStorage хранилище состояний диалогов по ID собеседника
+type StateStorage interface {
+	Load(userID int) (ConversationState, bool, error)
+	Save(userID int, state ConversationState) error
+	Delete(userID int) error
+}
+
+// MemoryStateStorage хранит состояния диалогов в памяти процесса
+type MemoryStateStorage struct {
+	mu     sync.RWMutex
+	states map[int]ConversationState
+}
+
+// NewMemoryStateStorage создает хранилище состояний в памяти
+func NewMemoryStateStorage() *MemoryStateStorage {
+	return &MemoryStateStorage{states: make(map[int]ConversationState)}
+}
+
+// Load возвращает состояние диалога
+func (s *MemoryStateStorage) Load(userID int) (ConversationState, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	state, ok := s.states[userID]
+	return state, ok, nil
+}
+
+// Save сохраняет состояние диалога
+func (s *MemoryStateStorage) Save(userID int, state ConversationState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[userID] = state
+	return nil
+}
+
+// Delete удаляет состояние диалога
+func (s *MemoryStateStorage) Delete(userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.states, userID)
+	return nil
+}
+
+// FileStateStorage хранит состояния диалогов в JSON файле
+type FileStateStorage struct {
+	mu     sync.Mutex
+	file   jsonFile
+	states map[int]ConversationState
+}
+
+// NewFileStateStorage создает файловое хранилище состояний и загружает сохраненные данные
+func NewFileStateStorage(path string) (*FileStateStorage, error) {
+	s := &FileStateStorage{
+		file:   jsonFile{path: path},
+		states: make(map[int]ConversationState),
+	}
+
+	if err := s.file.load(&s.states); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Load возвращает состояние диалога
+func (s *FileStateStorage) Load(userID int) (ConversationState, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.states[userID]
+	return state, ok, nil
+}
+
+// Save сохраняет состояние диалога и записывает файл
+func (s *FileStateStorage) Save(userID int, state ConversationState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.states[userID] = state
+	return s.file.save(s.states)
+}
+
+// Delete удаляет состояние диалога и записывает файл
+func (s *FileStateStorage) Delete(userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.states[userID]; !ok {
+		return nil
+	}
+	delete(s.states, userID)
+	return s.file.save(s.states)
+}
+
+// ContactStore хранилище собеседников, которые уже писали боту
+type ContactStore interface {
+	Known(userID int) (bool, error)
+	Add(userID int) error
+}
+
+// MemoryContactStore хранит известных собеседников в памяти процесса
+type MemoryContactStore struct {
+	mu       sync.RWMutex
+	contacts map[int]time.Time
+}
+
+// NewMemoryContactStore создает хранилище собеседников в памяти
+func NewMemoryContactStore() *MemoryContactStore {
+	return &MemoryContactStore{contacts: make(map[int]time.Time)}
+}
+
+// Known проверяет, писал ли собеседник раньше
+func (s *MemoryContactStore) Known(userID int) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.contacts[userID]
+	return ok, nil
+}
+
+// Add запоминает собеседника
+func (s *MemoryContactStore) Add(userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.contacts[userID]; !ok {
+		s.contacts[userID] = time.Now()
+	}
+	return nil
+}
+
+// FileContactStore хранит известных собеседников в JSON файле вместе со временем первого сообщения
+type FileContactStore struct {
+	mu       sync.Mutex
+	file     jsonFile
+	contacts map[int]time.Time
+}
+
+// NewFileContactStore создает файловое хранилище собеседников и загружает сохраненные данные
+func NewFileContactStore(path string) (*FileContactStore, error) {
+	s := &FileContactStore{
+		file:     jsonFile{path: path},
+		contacts: make(map[int]time.Time),
+	}
+
+	if err := s.file.load(&s.contacts); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Known проверяет, писал ли собеседник раньше
+func (s *FileContactStore) Known(userID int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.contacts[userID]
+	return ok, nil
+}
+
+// Add запоминает собеседника и записывает файл
+func (s *FileContactStore) Add(userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.contacts[userID]; ok {
+		return nil
+	}
+	s.contacts[userID] = time.Now()
+	return s.file.save(s.contacts)
+}
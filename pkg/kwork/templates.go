@@ -0,0 +1,306 @@
+package kwork
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+	"unicode"
+
+	"github.com/rtexty/gokwork/pkg/kwork/errors"
+	"github.com/rtexty/gokwork/pkg/kwork/types"
+)
+
+const (
+	// LanguageRu русский язык шаблонов
+	LanguageRu = "ru"
+	// LanguageEn английский язык шаблонов
+	LanguageEn = "en"
+
+	templateExt = ".tmpl"
+
+	// botProfileTTL время, в течение которого бот не перезагружает профиль аккаунта
+	botProfileTTL = 10 * time.Minute
+)
+
+// TemplateData переменные, доступные в шаблоне ответа
+type TemplateData struct {
+	// User профиль отправителя, nil если его не удалось получить
+	User *types.User
+	// Me профиль текущего аккаунта
+	Me *types.Actor
+	// Message входящее сообщение
+	Message *types.Message
+}
+
+// Name возвращает имя отправителя или его логин
+func (d TemplateData) Name() string {
+	if d.User == nil {
+		return ""
+	}
+	if d.User.Fullname != "" {
+		return d.User.Fullname
+	}
+	return d.User.Username
+}
+
+// Templates набор шаблонов ответов по языкам. Шаблоны загружаются из файлов
+// <dir>/<язык>/<имя>.tmpl и используют синтаксис text/template.
+type Templates struct {
+	dir             string
+	defaultLanguage string
+
+	mu        sync.RWMutex
+	sets      map[string]*template.Template
+	signature string
+}
+
+// LoadTemplates загружает шаблоны из каталога. Язык по умолчанию — русский.
+func LoadTemplates(dir string) (*Templates, error) {
+	t := &Templates{dir: dir, defaultLanguage: LanguageRu}
+	if err := t.Reload(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// SetDefaultLanguage задает язык, шаблоны которого используются,
+// если нужного языка или шаблона нет
+func (t *Templates) SetDefaultLanguage(lang string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.defaultLanguage = lang
+}
+
+// Reload перечитывает шаблоны. При ошибке остаются загруженные ранее шаблоны.
+func (t *Templates) Reload() error {
+	files, signature, err := t.scan()
+	if err != nil {
+		return err
+	}
+
+	sets := make(map[string]*template.Template)
+	for _, path := range files {
+		lang := filepath.Base(filepath.Dir(path))
+		name := strings.TrimSuffix(filepath.Base(path), templateExt)
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		set, ok := sets[lang]
+		if !ok {
+			set = template.New(lang)
+			sets[lang] = set
+		}
+		if _, err := set.New(name).Parse(string(content)); err != nil {
+			return fmt.Errorf("failed to parse template %s: %w", path, err)
+		}
+	}
+
+	t.mu.Lock()
+	t.sets = sets
+	t.signature = signature
+	t.mu.Unlock()
+
+	return nil
+}
+
+// Watch проверяет файлы шаблонов с периодом interval и перечитывает их
+// при изменении. Блокируется до отмены ctx.
+func (t *Templates) Watch(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			_, signature, err := t.scan()
+			if err != nil {
+				log.Printf("Failed to check templates: %v", err)
+				continue
+			}
+
+			t.mu.RLock()
+			changed := signature != t.signature
+			t.mu.RUnlock()
+			if !changed {
+				continue
+			}
+
+			if err := t.Reload(); err != nil {
+				log.Printf("Failed to reload templates: %v", err)
+				continue
+			}
+			log.Printf("Templates reloaded from %s", t.dir)
+		}
+	}
+}
+
+// scan находит файлы шаблонов и вычисляет отпечаток их имен, размеров и времени изменения
+func (t *Templates) scan() ([]string, string, error) {
+	files, err := filepath.Glob(filepath.Join(t.dir, "*", "*"+templateExt))
+	if err != nil {
+		return nil, "", err
+	}
+	sort.Strings(files)
+
+	var signature strings.Builder
+	for _, path := range files {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, "", err
+		}
+		fmt.Fprintf(&signature, "%s:%d:%d;", path, info.Size(), info.ModTime().UnixNano())
+	}
+
+	return files, signature.String(), nil
+}
+
+// Has проверяет, есть ли шаблон хотя бы на одном языке
+func (t *Templates) Has(name string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	for _, set := range t.sets {
+		if set.Lookup(name) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Render заполняет шаблон name на языке lang. Если такого шаблона нет,
+// используется шаблон языка по умолчанию.
+func (t *Templates) Render(lang, name string, data TemplateData) (string, error) {
+	t.mu.RLock()
+	tmpl := t.lookup(lang, name)
+	if tmpl == nil {
+		tmpl = t.lookup(t.defaultLanguage, name)
+	}
+	t.mu.RUnlock()
+
+	if tmpl == nil {
+		return "", errors.NewKworkBotError(fmt.Sprintf("template %q not found", name))
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render template %q: %w", name, err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+func (t *Templates) lookup(lang, name string) *template.Template {
+	set, ok := t.sets[lang]
+	if !ok {
+		return nil
+	}
+	return set.Lookup(name)
+}
+
+// Language выбирает язык ответа. Английский выбирается, только если
+// у аккаунта заполнено английское имя (FullnameEn), а отправитель пишет
+// и подписан латиницей.
+func (t *Templates) Language(data TemplateData) string {
+	if data.Me == nil || data.Me.FullnameEn == "" {
+		return t.defaultLanguage
+	}
+
+	if data.Message != nil && hasCyrillic(data.Message.Text) {
+		return LanguageRu
+	}
+	if data.User != nil && hasCyrillic(data.User.Fullname+data.User.Location) {
+		return LanguageRu
+	}
+	if data.Message != nil && hasLatin(data.Message.Text) {
+		return LanguageEn
+	}
+	return t.defaultLanguage
+}
+
+func hasCyrillic(s string) bool {
+	for _, r := range s {
+		if unicode.Is(unicode.Cyrillic, r) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasLatin(s string) bool {
+	for _, r := range s {
+		if unicode.Is(unicode.Latin, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// profile возвращает профиль аккаунта, загружая его не чаще раза в botProfileTTL
+func (b *Bot) profile(ctx context.Context) (*types.Actor, error) {
+	b.meMu.Lock()
+	defer b.meMu.Unlock()
+
+	if b.me != nil && time.Since(b.meLoaded) < botProfileTTL {
+		return b.me, nil
+	}
+
+	me, err := b.GetMe(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b.me = me
+	b.meLoaded = time.Now()
+	return me, nil
+}
+
+// TemplateData собирает переменные шаблона для входящего сообщения.
+// Профиль аккаунта кэшируется, профиль отправителя запрашивается каждый раз.
+func (b *Bot) TemplateData(ctx context.Context, msg *types.Message) (TemplateData, error) {
+	me, err := b.profile(ctx)
+	if err != nil {
+		return TemplateData{}, err
+	}
+
+	data := TemplateData{Me: me, Message: msg}
+
+	// Без профиля отправителя шаблон все равно можно заполнить
+	if user, err := b.GetUser(ctx, msg.FromID); err != nil {
+		log.Printf("Failed to get user %d for template: %v", msg.FromID, err)
+	} else {
+		data.User = user
+	}
+
+	return data, nil
+}
+
+// RenderReply заполняет шаблон ответа на сообщение на языке отправителя
+func (b *Bot) RenderReply(ctx context.Context, templates *Templates, name string, msg *types.Message) (string, error) {
+	data, err := b.TemplateData(ctx, msg)
+	if err != nil {
+		return "", err
+	}
+	return templates.Render(templates.Language(data), name, data)
+}
+
+// TemplateReply возвращает обработчик, отвечающий на сообщение шаблоном name
+func (b *Bot) TemplateReply(templates *Templates, name string) HandlerFunc {
+	return func(ctx context.Context, msg *types.Message) error {
+		text, err := b.RenderReply(ctx, templates, name, msg)
+		if err != nil {
+			return err
+		}
+		return msg.AnswerSimulation(ctx, text)
+	}
+}
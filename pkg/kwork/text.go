@@ -0,0 +1,34 @@
+package kwork
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rtexty/gokwork/pkg/kwork/types"
+)
+
+// MaxMessageLength максимальная длина одного сообщения в символах
+const MaxMessageLength = 4000
+
+// NormalizeMessageText приводит переводы строк к единому виду.
+// Отступы и пробелы в тексте сохраняются.
+func NormalizeMessageText(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.ReplaceAll(text, "\r", "\n")
+}
+
+// ValidateMessageLength проверяет, помещается ли текст в одно сообщение
+func ValidateMessageLength(text string) bool {
+	return utf8.RuneCountInString(text) <= MaxMessageLength
+}
+
+// SplitMessage разбивает длинный текст на части не длиннее limit символов.
+// Разрыв ищется сначала между абзацами, затем между строками и словами;
+// эмодзи и составные символы не разрываются.
+func SplitMessage(text string, limit int) []string {
+	if limit <= 0 {
+		limit = MaxMessageLength
+	}
+
+	return types.SplitText(NormalizeMessageText(text), limit)
+}
@@ -0,0 +1,166 @@
+package kwork
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+)
+
+// captureTransport сохраняет тела запросов и отвечает успехом
+type captureTransport struct {
+	mu     sync.Mutex
+	bodies []string
+}
+
+func (t *captureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	t.bodies = append(t.bodies, string(body))
+	t.mu.Unlock()
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(`{"success":true,"response":{}}`)),
+		Request:    req,
+	}, nil
+}
+
+func newCaptureClient(t *testing.T) (*Client, *captureTransport) {
+	t.Helper()
+
+	client, err := NewClient(Config{Login: "login", Password: "password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+
+	transport := &captureTransport{}
+	client.httpClient.Transport = transport
+	client.token = "token"
+	return client, transport
+}
+
+func TestSendMessageEncoding(t *testing.T) {
+	texts := []string{
+		"Привет, как дела?",
+		"a+b = c & d%20e",
+		"Эмодзи 👍🏽 и семья 👨‍👩‍👧",
+		`Кавычки "двойные" и 'одинарные' «ёлочки»`,
+		"Первая строка\nВторая строка\n\n    отступ",
+	}
+
+	for _, text := range texts {
+		client, transport := newCaptureClient(t)
+
+		if err := client.SendMessage(context.Background(), 42, text); err != nil {
+			t.Fatalf("SendMessage(%q): %v", text, err)
+		}
+		if len(transport.bodies) != 1 {
+			t.Fatalf("SendMessage(%q) sent %d requests, want 1", text, len(transport.bodies))
+		}
+
+		body := transport.bodies[0]
+		values, err := url.ParseQuery(body)
+		if err != nil {
+			t.Fatalf("invalid form body %q: %v", body, err)
+		}
+		if got := values.Get("text"); got != text {
+			t.Errorf("text decoded as %q, want %q (body %q)", got, text, body)
+		}
+		if values.Get("user_id") != "42" {
+			t.Errorf("user_id = %q, want 42", values.Get("user_id"))
+		}
+	}
+}
+
+func TestSendMessageCRLF(t *testing.T) {
+	client, transport := newCaptureClient(t)
+
+	if err := client.SendMessage(context.Background(), 1, "строка\r\nещё\rи ещё"); err != nil {
+		t.Fatal(err)
+	}
+
+	values, _ := url.ParseQuery(transport.bodies[0])
+	if got, want := values.Get("text"), "строка\nещё\nи ещё"; got != want {
+		t.Errorf("text = %q, want %q", got, want)
+	}
+}
+
+func TestSendMessageSplitsLongText(t *testing.T) {
+	client, transport := newCaptureClient(t)
+
+	paragraph := strings.Repeat("слово ", 500)
+	text := paragraph + "\n\n" + paragraph
+	if err := client.SendMessage(context.Background(), 1, text); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(transport.bodies) < 2 {
+		t.Fatalf("sent %d requests, want at least 2", len(transport.bodies))
+	}
+	for _, body := range transport.bodies {
+		values, _ := url.ParseQuery(body)
+		if n := utf8.RuneCountInString(values.Get("text")); n > MaxMessageLength {
+			t.Errorf("part has %d characters, limit %d", n, MaxMessageLength)
+		}
+	}
+}
+
+func TestSendMessageEmpty(t *testing.T) {
+	client, transport := newCaptureClient(t)
+
+	for _, text := range []string{"", "  \n\t "} {
+		if err := client.SendMessage(context.Background(), 1, text); err == nil {
+			t.Errorf("SendMessage(%q) succeeded, want error", text)
+		}
+	}
+	if len(transport.bodies) != 0 {
+		t.Errorf("sent %d requests for empty text", len(transport.bodies))
+	}
+}
+
+func TestSplitMessage(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  []string
+	}{
+		{"empty", "", 10, nil},
+		{"whitespace", " \n ", 10, nil},
+		{"fits exactly", "0123456789", 10, []string{"0123456789"}},
+		{"keeps indentation", "    код\n  ещё", 100, []string{"    код\n  ещё"}},
+		{"paragraphs", "первый абзац\n\nвторой абзац", 20, []string{"первый абзац", "второй абзац"}},
+		{"lines", "строка один\nстрока два", 15, []string{"строка один", "строка два"}},
+		{"sentences", "Привет! Как дела? Хорошо.", 20, []string{"Привет! Как дела?", "Хорошо."}},
+		{"words", "один два три четыре", 9, []string{"один два", "три", "четыре"}},
+		{"hard cut", "абвгдежзийклм", 5, []string{"абвгд", "ежзий", "клм"}},
+		{"emoji not torn", "ab👍🏽cd", 3, []string{"ab", "👍🏽c", "d"}},
+		{"zwj sequence", "ab👨‍👩‍👧", 6, []string{"ab", "👨‍👩‍👧"}},
+		{"default limit", strings.Repeat("я", MaxMessageLength), 0, []string{strings.Repeat("я", MaxMessageLength)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitMessage(tt.text, tt.limit)
+			if len(got) != len(tt.want) {
+				t.Fatalf("SplitMessage(%q, %d) = %q, want %q", tt.text, tt.limit, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("SplitMessage(%q, %d) = %q, want %q", tt.text, tt.limit, got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -30,21 +30,30 @@ type Dialog struct {
 
 // InboxMessage представляет сообщение в диалоге
 type InboxMessage struct {
-	MessageID          int         `json:"message_id"`
-	ToID               int         `json:"to_id"`
-	ToUsername         string      `json:"to_username"`
-	ToLiveDate         int         `json:"to_live_date"`
-	FromID             int         `json:"from_id"`
-	FromUsername       string      `json:"from_username"`
-	FromLiveDate       int         `json:"from_live_date"`
-	FromProfilePicture string      `json:"from_profilepicture"`
-	Message            string      `json:"message"`
-	Time               int         `json:"time"`
-	Unread             bool        `json:"unread"`
-	Type               string      `json:"type,omitempty"`
-	Status             string      `json:"status"`
-	CreatedOrderID     interface{} `json:"created_order_id,omitempty"`
-	Forwarded          bool        `json:"forwarded"`
-	UpdatedAt          int         `json:"updated_at,omitempty"`
-	MessagePage        int         `json:"message_page"`
+	MessageID          int           `json:"message_id"`
+	ToID               int           `json:"to_id"`
+	ToUsername         string        `json:"to_username"`
+	ToLiveDate         int           `json:"to_live_date"`
+	FromID             int           `json:"from_id"`
+	FromUsername       string        `json:"from_username"`
+	FromLiveDate       int           `json:"from_live_date"`
+	FromProfilePicture string        `json:"from_profilepicture"`
+	Message            string        `json:"message"`
+	Time               int           `json:"time"`
+	Unread             bool          `json:"unread"`
+	Type               string        `json:"type,omitempty"`
+	Status             string        `json:"status"`
+	CreatedOrderID     interface{}   `json:"created_order_id,omitempty"`
+	Forwarded          bool          `json:"forwarded"`
+	UpdatedAt          int           `json:"updated_at,omitempty"`
+	MessagePage        int           `json:"message_page"`
+	Files              []MessageFile `json:"files,omitempty"`
+}
+
+// MessageFile представляет файл, прикрепленный к сообщению
+type MessageFile struct {
+	FileID   int    `json:"id"`
+	FileName string `json:"fname"`
+	URL      string `json:"url"`
+	Size     int    `json:"size"`
 }
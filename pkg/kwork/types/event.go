@@ -1,14 +1,16 @@
 package types
 
+import "encoding/json"
+
 // EventType константы типов событий
 const (
-	EventTypeIsTyping         = "is_typing"
-	EventTypeNotify           = "notify"
-	EventTypeNewMessage       = "new_inbox"
-	EventTypePopUpNotify      = "pop_up_notify"
-	EventTypeMessageDelete    = "inbox_message_delete"
+	EventTypeIsTyping          = "is_typing"
+	EventTypeNotify            = "notify"
+	EventTypeNewMessage        = "new_inbox"
+	EventTypePopUpNotify       = "pop_up_notify"
+	EventTypeMessageDelete     = "inbox_message_delete"
 	EventTypeRemovePopUpNotify = "remove_pop_up_notify"
-	EventTypeDialogUpdate     = "dialog_updated"
+	EventTypeDialogUpdate      = "dialog_updated"
 )
 
 // Notify константы уведомлений
@@ -21,3 +23,90 @@ type BaseEvent struct {
 	Event string                 `json:"event"`
 	Data  map[string]interface{} `json:"data"`
 }
+
+// Event представляет типизированное событие WebSocket
+type Event interface {
+	// EventType возвращает тип события
+	EventType() string
+}
+
+// NewMessageEvent новое сообщение в диалоге
+type NewMessageEvent struct {
+	FromID      int
+	ToUserID    int
+	InboxID     int
+	Text        string
+	Title       string
+	LastMessage map[string]interface{}
+}
+
+// EventType возвращает тип события
+func (e *NewMessageEvent) EventType() string { return EventTypeNewMessage }
+
+// NotifyEvent уведомление об изменениях (новые сообщения, заказы и т.д.)
+type NotifyEvent struct {
+	NewMessage bool
+	DialogData []map[string]interface{}
+	Data       map[string]interface{}
+}
+
+// EventType возвращает тип события
+func (e *NotifyEvent) EventType() string { return EventTypeNotify }
+
+// TypingEvent собеседник набирает сообщение
+type TypingEvent struct {
+	FromID   int
+	ToUserID int
+	Data     map[string]interface{}
+}
+
+// EventType возвращает тип события
+func (e *TypingEvent) EventType() string { return EventTypeIsTyping }
+
+// MessageDeletedEvent сообщение в диалоге удалено
+type MessageDeletedEvent struct {
+	MessageID int
+	UserID    int
+	Data      map[string]interface{}
+}
+
+// EventType возвращает тип события
+func (e *MessageDeletedEvent) EventType() string { return EventTypeMessageDelete }
+
+// DialogUpdatedEvent диалог изменился (прочитан, архивирован и т.д.)
+type DialogUpdatedEvent struct {
+	UserID int
+	Data   map[string]interface{}
+}
+
+// EventType возвращает тип события
+func (e *DialogUpdatedEvent) EventType() string { return EventTypeDialogUpdate }
+
+// PopUpNotifyEvent всплывающее уведомление
+type PopUpNotifyEvent struct {
+	ID       int
+	Type     string
+	Username string
+	Data     map[string]interface{}
+}
+
+// EventType возвращает тип события
+func (e *PopUpNotifyEvent) EventType() string { return EventTypePopUpNotify }
+
+// RemovePopUpNotifyEvent всплывающее уведомление скрыто
+type RemovePopUpNotifyEvent struct {
+	ID   int
+	Data map[string]interface{}
+}
+
+// EventType возвращает тип события
+func (e *RemovePopUpNotifyEvent) EventType() string { return EventTypeRemovePopUpNotify }
+
+// UnknownEvent событие неизвестного типа с исходным JSON
+type UnknownEvent struct {
+	Event string
+	Raw   json.RawMessage
+}
+
+// EventType возвращает тип события
+func (e *UnknownEvent) EventType() string { return e.Event }
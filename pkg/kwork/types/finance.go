@@ -0,0 +1,59 @@
+package types
+
+// OperationType тип финансовой операции
+type OperationType string
+
+// Типы финансовых операций
+const (
+	OperationOrderPayment OperationType = "order_payment"
+	OperationRefund       OperationType = "refund"
+	OperationWithdrawal   OperationType = "withdrawal"
+	OperationCommission   OperationType = "commission"
+	OperationBonus        OperationType = "bonus"
+)
+
+// Balance представляет баланс пользователя
+type Balance struct {
+	TotalAmount int    `json:"total_amount"`
+	HoldAmount  int    `json:"hold_amount"`
+	FreeAmount  int    `json:"free_amount"`
+	Currency    string `json:"currency"`
+}
+
+// Transaction представляет финансовую операцию
+type Transaction struct {
+	ID          int           `json:"id"`
+	Type        OperationType `json:"type"`
+	Amount      int           `json:"amount"`
+	Currency    string        `json:"currency"`
+	Time        int           `json:"time"`
+	OrderID     int           `json:"order_id,omitempty"`
+	Description string        `json:"description"`
+	Status      string        `json:"status"`
+}
+
+// IsIncome проверяет, увеличивает ли операция баланс
+func (t *Transaction) IsIncome() bool {
+	return t.Type == OperationOrderPayment || t.Type == OperationBonus
+}
+
+// TransactionsPage представляет страницу истории операций
+type TransactionsPage struct {
+	Transactions []Transaction `json:"operations"`
+	Page         int           `json:"page"`
+	Pages        int           `json:"pages"`
+}
+
+// MonthlyStatement представляет выписку по операциям за месяц
+type MonthlyStatement struct {
+	Year         int
+	Month        int
+	Currency     string
+	Income       int
+	Refunds      int
+	Withdrawals  int
+	Commissions  int
+	Bonuses      int
+	Net          int
+	Transactions []Transaction
+}
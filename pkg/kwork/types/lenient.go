@@ -0,0 +1,164 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// decodeList разбирает список, который API может прислать массивом, объектом
+// с элементами по ключам, false, пустой строкой или null
+func decodeList[T any](data []byte, dst *[]T) error {
+	data = bytes.TrimSpace(data)
+
+	switch {
+	case len(data) == 0, bytes.Equal(data, []byte("null")), bytes.Equal(data, []byte("false")), bytes.Equal(data, []byte(`""`)):
+		*dst = nil
+		return nil
+
+	case data[0] == '{':
+		var items map[string]json.RawMessage
+		if err := json.Unmarshal(data, &items); err != nil {
+			return err
+		}
+
+		keys := make([]string, 0, len(items))
+		for key := range items {
+			keys = append(keys, key)
+		}
+		// Ключи обычно числовые индексы, сохраняем их порядок
+		sort.Slice(keys, func(i, j int) bool {
+			a, errA := strconv.Atoi(keys[i])
+			b, errB := strconv.Atoi(keys[j])
+			if errA == nil && errB == nil {
+				return a < b
+			}
+			return keys[i] < keys[j]
+		})
+
+		list := make([]T, 0, len(keys))
+		for _, key := range keys {
+			var item T
+			if err := json.Unmarshal(items[key], &item); err != nil {
+				return err
+			}
+			list = append(list, item)
+		}
+		*dst = list
+		return nil
+	}
+
+	var list []T
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*dst = list
+	return nil
+}
+
+// decodeLenient разбирает объект в структуру v. Если строгий разбор не удался,
+// значения приводятся к типам полей: числа в строках — к числам, числа —
+// к строкам, 0/1 — к bool, а пустые значения вместо объектов и списков пропускаются.
+func decodeLenient(data []byte, v any) error {
+	err := json.Unmarshal(data, v)
+	if err == nil {
+		return nil
+	}
+
+	var fields map[string]interface{}
+	if json.Unmarshal(data, &fields) != nil {
+		return err
+	}
+
+	rt := reflect.TypeOf(v).Elem()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		value, ok := fields[name]
+		if !ok {
+			continue
+		}
+
+		if converted, ok := convertValue(value, field.Type); ok {
+			fields[name] = converted
+		} else {
+			delete(fields, name)
+		}
+	}
+
+	normalized, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(normalized, v)
+}
+
+// convertValue приводит значение JSON к типу поля. false — значение нельзя привести
+// и поле нужно пропустить.
+func convertValue(value interface{}, t reflect.Type) (interface{}, bool) {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		switch v := value.(type) {
+		case float64, nil:
+			return v, true
+		case string:
+			n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			return n, err == nil
+		case bool:
+			if v {
+				return 1, true
+			}
+			return 0, true
+		}
+		return nil, false
+
+	case reflect.String:
+		switch v := value.(type) {
+		case string, nil:
+			return v, true
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), true
+		case bool:
+			return strconv.FormatBool(v), true
+		}
+		return nil, false
+
+	case reflect.Bool:
+		switch v := value.(type) {
+		case bool, nil:
+			return v, true
+		case float64:
+			return v != 0, true
+		case string:
+			return v != "" && v != "0" && v != "false", true
+		}
+		return nil, false
+
+	case reflect.Slice, reflect.Map, reflect.Struct:
+		// Сложные типы со своим UnmarshalJSON разбирают значение сами
+		if reflect.PointerTo(t).Implements(reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()) {
+			return value, true
+		}
+		switch v := value.(type) {
+		case []interface{}, nil:
+			if t.Kind() == reflect.Slice {
+				return v, true
+			}
+		case map[string]interface{}:
+			if t.Kind() != reflect.Slice {
+				return v, true
+			}
+		}
+		return nil, false
+	}
+
+	return value, true
+}
@@ -2,7 +2,9 @@ package types
 
 import (
 	"context"
-	"time"
+	"fmt"
+	"hash/fnv"
+	"strings"
 )
 
 // Message представляет сообщение от бота
@@ -13,7 +15,29 @@ type Message struct {
 	InboxID     int
 	Title       string
 	LastMessage map[string]interface{}
-	api         MessageSender
+	// MessageID идентификатор сообщения в диалоге, 0 если неизвестен
+	MessageID int
+	// Time время отправки сообщения (unix), 0 если неизвестно
+	Time int
+	// Files файлы, прикрепленные к сообщению
+	Files []MessageFile
+	api   MessageSender
+}
+
+// Key возвращает ключ, идентифицирующий сообщение независимо от того,
+// из какого события WebSocket оно получено
+func (m *Message) Key() string {
+	if m.MessageID != 0 {
+		return fmt.Sprintf("id:%d", m.MessageID)
+	}
+	return m.ContentKey()
+}
+
+// ContentKey возвращает ключ по отправителю и тексту сообщения
+func (m *Message) ContentKey() string {
+	h := fnv.New64a()
+	h.Write([]byte(strings.TrimSpace(m.Text)))
+	return fmt.Sprintf("from:%d:%x", m.FromID, h.Sum64())
 }
 
 // MessageSender интерфейс для отправки сообщений
@@ -35,14 +59,22 @@ func NewMessage(api MessageSender, fromID int, text string, toUserID, inboxID in
 	}
 }
 
+// WithSender возвращает копию сообщения, ответы на которую отправляются через api
+func (m *Message) WithSender(api MessageSender) *Message {
+	c := *m
+	c.api = api
+	return &c
+}
+
+// Sender возвращает API, через которое отправляются ответы на сообщение
+func (m *Message) Sender() MessageSender {
+	return m.api
+}
+
 // AnswerSimulation отправляет реалистичный ответ с симуляцией набора текста
+// с настройками по умолчанию (см. AnswerTyping)
 func (m *Message) AnswerSimulation(ctx context.Context, text string) error {
-	time.Sleep(2 * time.Second)
-	if err := m.api.SetTyping(ctx, m.FromID); err != nil {
-		return err
-	}
-	time.Sleep(2 * time.Second)
-	return m.api.SendMessage(ctx, m.FromID, text)
+	return m.AnswerTyping(ctx, text, TypingOptions{})
 }
 
 // FastAnswer отправляет быстрый ответ без симуляции
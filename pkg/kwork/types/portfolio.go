@@ -1,22 +1,77 @@
 package types
 
+// PortfolioImage представляет изображение в портфолио
+type PortfolioImage struct {
+	ID      int    `json:"id"`
+	URL     string `json:"url"`
+	Preview string `json:"preview,omitempty"`
+	Width   int    `json:"width,omitempty"`
+	Height  int    `json:"height,omitempty"`
+}
+
+// PortfolioVideo представляет видео в портфолио
+type PortfolioVideo struct {
+	ID      int    `json:"id"`
+	URL     string `json:"url"`
+	Preview string `json:"preview,omitempty"`
+}
+
 // PortfolioItem представляет элемент портфолио
 type PortfolioItem struct {
-	ID            int                      `json:"id"`
-	Title         string                   `json:"title"`
-	OrderID       interface{}              `json:"order_id"` // может быть int или string
-	CategoryID    int                      `json:"category_id"`
-	CategoryName  string                   `json:"category_name"`
-	Type          string                   `json:"type"`
-	Photo         string                   `json:"photo"`
-	Video         string                   `json:"video"`
-	Likes         int                      `json:"likes"`
-	LikesDirty    int                      `json:"likes_dirty"`
-	Views         int                      `json:"views"`
-	ViewsDirty    int                      `json:"views_dirty"`
-	CommentsCount int                      `json:"comments_count"`
-	IsLiked       bool                     `json:"is_liked"`
-	Images        []map[string]interface{} `json:"images,omitempty"`
-	Videos        []map[string]interface{} `json:"videos,omitempty"`
-	DuplicateFrom string                   `json:"duplicate_from"`
+	ID            int             `json:"id"`
+	Title         string          `json:"title"`
+	Description   string          `json:"description,omitempty"`
+	OrderID       interface{}     `json:"order_id"` // может быть int или string
+	CategoryID    int             `json:"category_id"`
+	CategoryName  string          `json:"category_name"`
+	Type          string          `json:"type"`
+	Photo         string          `json:"photo"`
+	Video         string          `json:"video"`
+	Likes         int             `json:"likes"`
+	LikesDirty    int             `json:"likes_dirty"`
+	Views         int             `json:"views"`
+	ViewsDirty    int             `json:"views_dirty"`
+	CommentsCount int             `json:"comments_count"`
+	IsLiked       bool            `json:"is_liked"`
+	Images        PortfolioImages `json:"images,omitempty"`
+	Videos        PortfolioVideos `json:"videos,omitempty"`
+	DuplicateFrom string          `json:"duplicate_from"`
+}
+
+// PortfolioItems список элементов портфолио. API присылает пустой список
+// как false или {}, а непустой — массивом или объектом по индексам.
+type PortfolioItems []PortfolioItem
+
+// PortfolioImages список изображений портфолио
+type PortfolioImages []PortfolioImage
+
+// PortfolioVideos список видео портфолио
+type PortfolioVideos []PortfolioVideo
+
+func (l *PortfolioItems) UnmarshalJSON(data []byte) error {
+	return decodeList(data, (*[]PortfolioItem)(l))
+}
+
+func (l *PortfolioImages) UnmarshalJSON(data []byte) error {
+	return decodeList(data, (*[]PortfolioImage)(l))
+}
+
+func (l *PortfolioVideos) UnmarshalJSON(data []byte) error {
+	return decodeList(data, (*[]PortfolioVideo)(l))
+}
+
+// UnmarshalJSON допускает ID и счётчики строками, а is_liked числом
+func (p *PortfolioItem) UnmarshalJSON(data []byte) error {
+	type plain PortfolioItem
+	return decodeLenient(data, (*plain)(p))
+}
+
+func (i *PortfolioImage) UnmarshalJSON(data []byte) error {
+	type plain PortfolioImage
+	return decodeLenient(data, (*plain)(i))
+}
+
+func (v *PortfolioVideo) UnmarshalJSON(data []byte) error {
+	type plain PortfolioVideo
+	return decodeLenient(data, (*plain)(v))
 }
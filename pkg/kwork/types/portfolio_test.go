@@ -0,0 +1,86 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestUserPortfolioShapes(t *testing.T) {
+	tests := []struct {
+		name      string
+		portfolio string
+		want      int
+	}{
+		{"missing", ``, 0},
+		{"null", `,"portfolio_list":null`, 0},
+		{"false", `,"portfolio_list":false`, 0},
+		{"empty object", `,"portfolio_list":{}`, 0},
+		{"empty array", `,"portfolio_list":[]`, 0},
+		{"array", `,"portfolio_list":[{"id":1},{"id":2}]`, 2},
+		{"indexed object", `,"portfolio_list":{"1":{"id":2},"0":{"id":1}}`, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var user User
+			data := `{"id":"7","username":"seller"` + tt.portfolio + `}`
+			if err := json.Unmarshal([]byte(data), &user); err != nil {
+				t.Fatalf("unmarshal %s: %v", data, err)
+			}
+			if user.Username != "seller" {
+				t.Errorf("Username = %q, want seller", user.Username)
+			}
+			if len(user.PortfolioList) != tt.want {
+				t.Fatalf("len(PortfolioList) = %d, want %d", len(user.PortfolioList), tt.want)
+			}
+			for i, item := range user.PortfolioList {
+				if item.ID != i+1 {
+					t.Errorf("PortfolioList[%d].ID = %d, want %d", i, item.ID, i+1)
+				}
+			}
+		})
+	}
+}
+
+func TestPortfolioItemLenient(t *testing.T) {
+	data := `{
+		"id": "15",
+		"title": "Логотип",
+		"order_id": "abc",
+		"category_id": "3",
+		"likes": 4,
+		"views": "120",
+		"is_liked": 1,
+		"images": [{"id": "9", "url": "https://example.com/1.jpg", "width": "800"}],
+		"videos": false,
+		"duplicate_from": 12
+	}`
+
+	var item PortfolioItem
+	if err := json.Unmarshal([]byte(data), &item); err != nil {
+		t.Fatal(err)
+	}
+
+	if item.ID != 15 || item.CategoryID != 3 || item.Views != 120 || item.Likes != 4 {
+		t.Errorf("numbers decoded as %+v", item)
+	}
+	if !item.IsLiked {
+		t.Error("IsLiked = false, want true")
+	}
+	if item.DuplicateFrom != "12" {
+		t.Errorf("DuplicateFrom = %q, want 12", item.DuplicateFrom)
+	}
+	if len(item.Images) != 1 || item.Images[0].ID != 9 || item.Images[0].Width != 800 {
+		t.Errorf("Images = %+v", item.Images)
+	}
+	if len(item.Videos) != 0 {
+		t.Errorf("Videos = %+v, want empty", item.Videos)
+	}
+}
+
+func TestPortfolioItemInvalid(t *testing.T) {
+	var items PortfolioItems
+	if err := json.Unmarshal([]byte(`"broken"`), &items); err == nil {
+		t.Error("unmarshal of a string succeeded, want error")
+	}
+}
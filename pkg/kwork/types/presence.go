@@ -0,0 +1,19 @@
+package types
+
+import "time"
+
+// Presence представляет онлайн-статус пользователя
+type Presence struct {
+	UserID    int
+	Online    bool
+	LiveDate  int
+	UpdatedAt time.Time
+}
+
+// LastSeen возвращает время последней активности пользователя
+func (p Presence) LastSeen() time.Time {
+	if p.Online {
+		return p.UpdatedAt
+	}
+	return time.Unix(int64(p.LiveDate), 0)
+}
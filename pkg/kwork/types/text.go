@@ -0,0 +1,77 @@
+package types
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const zeroWidthJoiner = '\u200d'
+
+// SplitText разбивает текст на части не длиннее limit символов.
+// Разрыв ищется сначала между абзацами, затем между строками, предложениями
+// и словами; эмодзи и составные символы не разрываются. Пробелы обрезаются
+// только на местах разрыва, отступы внутри текста сохраняются. При limit <= 0
+// текст возвращается целиком, пустой текст дает nil.
+func SplitText(text string, limit int) []string {
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+	if limit <= 0 || utf8.RuneCountInString(text) <= limit {
+		return []string{text}
+	}
+
+	var parts []string
+	runes := []rune(text)
+
+	for len(runes) > limit {
+		cut := findSplitPoint(runes, limit)
+		part := strings.TrimRightFunc(string(runes[:cut]), unicode.IsSpace)
+		if strings.TrimSpace(part) != "" {
+			parts = append(parts, part)
+		}
+		runes = []rune(strings.TrimLeft(string(runes[cut:]), "\n"))
+	}
+
+	if rest := string(runes); strings.TrimSpace(rest) != "" {
+		parts = append(parts, rest)
+	}
+
+	return parts
+}
+
+// findSplitPoint ищет позицию разрыва не дальше limit
+func findSplitPoint(runes []rune, limit int) int {
+	window := string(runes[:limit])
+
+	// Не разрываем слишком рано, чтобы части не получались крошечными
+	minCut := limit / 2
+
+	for _, sep := range []string{"\n\n", "\n", ". ", "! ", "? ", " "} {
+		if idx := strings.LastIndex(window, sep); idx >= 0 {
+			cut := utf8.RuneCountInString(window[:idx]) + utf8.RuneCountInString(sep)
+			if cut >= minCut {
+				return cut
+			}
+		}
+	}
+
+	// Жесткий разрыв по границе символа, не разделяя составные эмодзи
+	cut := limit
+	for cut > 1 && isJoinedRune(runes, cut) {
+		cut--
+	}
+	return cut
+}
+
+// isJoinedRune проверяет, связан ли символ на позиции i с предыдущим
+func isJoinedRune(runes []rune, i int) bool {
+	if i <= 0 || i >= len(runes) {
+		return false
+	}
+	r, prev := runes[i], runes[i-1]
+	return prev == zeroWidthJoiner || r == zeroWidthJoiner ||
+		unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Me, r) ||
+		unicode.Is(unicode.Variation_Selector, r) ||
+		(r >= 0x1F3FB && r <= 0x1F3FF) // модификаторы цвета кожи
+}
@@ -0,0 +1,145 @@
+package types
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rtexty/gokwork/pkg/kwork/errors"
+	"github.com/rtexty/gokwork/pkg/kwork/internal/wait"
+)
+
+const (
+	defaultTypingReadDelay       = time.Second
+	defaultTypingCharsPerSecond  = 8
+	defaultTypingMinDelay        = time.Second
+	defaultTypingMaxDelay        = 20 * time.Second
+	defaultTypingJitter          = 0.3
+	defaultTypingRefreshInterval = 4 * time.Second
+	defaultTypingChunkPause      = time.Second
+)
+
+// TypingOptions настройки симуляции набора текста.
+// Нулевые значения заменяются значениями по умолчанию.
+type TypingOptions struct {
+	// ReadDelay пауза перед началом набора, будто сообщение читают. По умолчанию 1 секунда.
+	ReadDelay time.Duration
+	// CharsPerSecond скорость набора в символах в секунду, по умолчанию 8
+	CharsPerSecond float64
+	// MinDelay и MaxDelay ограничивают время набора одной части ответа,
+	// по умолчанию 1 и 20 секунд
+	MinDelay time.Duration
+	MaxDelay time.Duration
+	// Jitter доля случайного разброса задержек, по умолчанию 0.3.
+	// Отрицательное значение отключает разброс.
+	Jitter float64
+	// RefreshInterval период повторной отправки статуса "печатает", по умолчанию 4 секунды
+	RefreshInterval time.Duration
+	// ChunkSize максимальная длина части ответа в символах. Длинный ответ
+	// разбивается по абзацам, предложениям и словам. 0 — не разбивать.
+	ChunkSize int
+	// ChunkPause пауза между частями ответа, по умолчанию 1 секунда
+	ChunkPause time.Duration
+}
+
+func (o TypingOptions) withDefaults() TypingOptions {
+	if o.ReadDelay <= 0 {
+		o.ReadDelay = defaultTypingReadDelay
+	}
+	if o.CharsPerSecond <= 0 {
+		o.CharsPerSecond = defaultTypingCharsPerSecond
+	}
+	if o.MinDelay <= 0 {
+		o.MinDelay = defaultTypingMinDelay
+	}
+	if o.MaxDelay <= 0 {
+		o.MaxDelay = defaultTypingMaxDelay
+	}
+	if o.MaxDelay < o.MinDelay {
+		o.MaxDelay = o.MinDelay
+	}
+	if o.Jitter == 0 {
+		o.Jitter = defaultTypingJitter
+	}
+	if o.RefreshInterval <= 0 {
+		o.RefreshInterval = defaultTypingRefreshInterval
+	}
+	if o.ChunkPause <= 0 {
+		o.ChunkPause = defaultTypingChunkPause
+	}
+	return o
+}
+
+// TypingDuration возвращает время набора текста: пропорционально длине,
+// со случайным разбросом и в пределах MinDelay..MaxDelay
+func (o TypingOptions) TypingDuration(text string) time.Duration {
+	o = o.withDefaults()
+
+	chars := utf8.RuneCountInString(text)
+	d := o.jitter(time.Duration(float64(chars) / o.CharsPerSecond * float64(time.Second)))
+
+	if d < o.MinDelay {
+		return o.MinDelay
+	}
+	if d > o.MaxDelay {
+		return o.MaxDelay
+	}
+	return d
+}
+
+// jitter случайно изменяет задержку на долю Jitter в обе стороны
+func (o TypingOptions) jitter(d time.Duration) time.Duration {
+	if o.Jitter <= 0 || d <= 0 {
+		return d
+	}
+	factor := 1 + o.Jitter*(2*rand.Float64()-1)
+	return time.Duration(float64(d) * factor)
+}
+
+// AnswerTyping отправляет ответ с симуляцией набора текста по настройкам opts.
+// Длинный ответ с заданным ChunkSize отправляется несколькими сообщениями.
+// Ожидание прерывается при отмене ctx.
+func (m *Message) AnswerTyping(ctx context.Context, text string, opts TypingOptions) error {
+	chunks := SplitText(text, opts.ChunkSize)
+	if len(chunks) == 0 {
+		return errors.NewKworkError("message text is empty")
+	}
+
+	opts = opts.withDefaults()
+
+	if !wait.Sleep(ctx, opts.jitter(opts.ReadDelay)) {
+		return ctx.Err()
+	}
+
+	for i, chunk := range chunks {
+		if i > 0 && !wait.Sleep(ctx, opts.jitter(opts.ChunkPause)) {
+			return ctx.Err()
+		}
+		if err := m.typeFor(ctx, opts.TypingDuration(chunk), opts.RefreshInterval); err != nil {
+			return err
+		}
+		if err := m.api.SendMessage(ctx, m.FromID, chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// typeFor показывает статус "печатает" в течение d, обновляя его каждые refresh
+func (m *Message) typeFor(ctx context.Context, d, refresh time.Duration) error {
+	deadline := time.Now().Add(d)
+	for {
+		if err := m.api.SetTyping(ctx, m.FromID); err != nil {
+			return err
+		}
+
+		left := time.Until(deadline)
+		if left <= 0 {
+			return nil
+		}
+		if !wait.Sleep(ctx, min(left, refresh)) {
+			return ctx.Err()
+		}
+	}
+}
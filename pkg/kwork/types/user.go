@@ -33,6 +33,6 @@ type User struct {
 	Profession               string          `json:"profession,omitempty"`
 	KworksCount              int             `json:"kworks_count"`
 	Kworks                   []KworkObject   `json:"kworks"`
-	PortfolioList            interface{}     `json:"portfolio_list,omitempty"`
+	PortfolioList            PortfolioItems  `json:"portfolio_list,omitempty"`
 	Reviews                  []Review        `json:"reviews,omitempty"`
 }
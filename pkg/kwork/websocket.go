@@ -3,44 +3,156 @@ package kwork
 import (
 	"context"
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
 	"log"
+	"net"
+	"strconv"
 	"time"
 
-	"github.com/gorilla/websocket"
+	"github.com/rtexty/gokwork/pkg/kwork/errors"
+	"github.com/rtexty/gokwork/pkg/kwork/internal/wait"
 	"github.com/rtexty/gokwork/pkg/kwork/types"
 )
 
-// MessageListener слушает сообщения через WebSocket
+// MessageListener слушает сообщения через WebSocket.
+// После каждого подключения досылает входящие сообщения, пропущенные
+// пока соединение было разорвано.
 func (c *Client) MessageListener(ctx context.Context, messageChan chan<- *types.Message) error {
+	gaps := newGapFiller(c)
+
+	send := func(msg *types.Message) {
+		select {
+		case messageChan <- msg:
+		case <-ctx.Done():
+		}
+	}
+
+	fillGaps := func() {
+		if err := gaps.reconcile(ctx, send); err != nil && ctx.Err() == nil {
+			log.Printf("Failed to fill message gap: %v", err)
+		}
+	}
+
+	removeHook := c.addConnectHook(func(context.Context) { fillGaps() })
+	defer removeHook()
+
+	sub := c.Subscribe(ctx, SubscribeOptions{
+		Filter: EventTypes(types.EventTypeNewMessage, types.EventTypeNotify, types.EventTypePopUpNotify),
+	})
+	defer sub.Unsubscribe()
+
+	// Соединение уже установлено другим подписчиком, хук подключения не сработает
+	if c.isConnected() {
+		fillGaps()
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		default:
-			if err := c.listenMessagesOnce(ctx, messageChan); err != nil {
-				log.Printf("WebSocket error: %v, reconnecting in 10 seconds...", err)
-				time.Sleep(10 * time.Second)
-				continue
+		case event, ok := <-sub.C():
+			if !ok {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				return c.listenerError()
+			}
+
+			for _, msg := range c.processEvent(ctx, event) {
+				if gaps.observe(msg) {
+					send(msg)
+				}
 			}
 		}
 	}
 }
 
-func (c *Client) listenMessagesOnce(ctx context.Context, messageChan chan<- *types.Message) error {
+// Events возвращает канал со всеми событиями WebSocket.
+// Канал закрывается после отмены контекста. Если канал не успевают читать,
+// самые старые события выбрасываются, чтобы не задерживать других подписчиков;
+// для другого поведения используйте Subscribe.
+func (c *Client) Events(ctx context.Context) <-chan types.Event {
+	return c.Subscribe(ctx, SubscribeOptions{Policy: PolicyDropOldest}).C()
+}
+
+// listenEvents слушает события WebSocket с переподключением по политике c.reconnect.
+// onConnected, если задан, вызывается после каждого подключения до чтения событий.
+func (c *Client) listenEvents(ctx context.Context, onConnected func(context.Context), handle func(types.Event)) error {
+	attempt := 0
+
+	for {
+		connectedAt, err := c.listenEventsOnce(ctx, onConnected, handle)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if !connectedAt.IsZero() {
+			// Считаем попытки заново, только если соединение продержалось:
+			// сервер, который принимает и сразу рвет соединение, должен исчерпать попытки
+			if time.Since(connectedAt) >= c.reconnect.StableAfter {
+				attempt = 0
+			}
+			if c.onDisconnect != nil {
+				c.onDisconnect(err)
+			}
+		}
+
+		attempt++
+		if c.reconnect.exhausted(attempt) {
+			return errors.NewKworkError(fmt.Sprintf("websocket reconnect failed after %d attempts: %v", c.reconnect.MaxAttempts, err))
+		}
+
+		delay := c.reconnect.Delay(attempt)
+		log.Printf("WebSocket error: %v, reconnecting in %s (attempt %d)...", err, delay.Round(time.Millisecond), attempt)
+
+		if c.onReconnectAttempt != nil {
+			c.onReconnectAttempt(attempt, delay)
+		}
+
+		if !wait.Sleep(ctx, delay) {
+			return ctx.Err()
+		}
+	}
+}
+
+// listenEventsOnce подключается к WebSocket и читает события до ошибки.
+// connectedAt время установки соединения, нулевое, если подключиться не удалось.
+func (c *Client) listenEventsOnce(ctx context.Context, onConnected func(context.Context), handle func(types.Event)) (connectedAt time.Time, err error) {
 	channel, err := c.getChannel(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get channel: %w", err)
+		return time.Time{}, fmt.Errorf("failed to get channel: %w", err)
 	}
 
 	uri := fmt.Sprintf("wss://notice.kwork.ru/ws/public/%s", channel)
 
-	conn, _, err := websocket.DefaultDialer.Dial(uri, nil)
+	dialer, report, err := c.websocketDialer()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	conn, _, err := dialer.DialContext(ctx, uri, nil)
+	if ctx.Err() == nil {
+		report(err)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to connect to websocket: %w", err)
+		// Канал мог устареть, при следующей попытке запросим новый
+		c.resetChannel()
+		return time.Time{}, fmt.Errorf("failed to connect to websocket: %w", err)
 	}
 	defer conn.Close()
 
+	connectedAt = time.Now()
+	c.setConnected(true)
+	defer c.setConnected(false)
+
+	if c.onConnect != nil {
+		c.onConnect()
+	}
+	if onConnected != nil {
+		onConnected(ctx)
+	}
+
 	// Канал для закрытия при отмене контекста
 	done := make(chan struct{})
 	defer close(done)
@@ -54,174 +166,345 @@ func (c *Client) listenMessagesOnce(ctx context.Context, messageChan chan<- *typ
 		}
 	}()
 
+	hb := startHeartbeat(conn, c.keepalive, done)
+
 	for {
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
+			return connectedAt, ctx.Err()
 		default:
 			_, message, err := conn.ReadMessage()
 			if err != nil {
-				return fmt.Errorf("failed to read message: %w", err)
+				var netErr net.Error
+				if stderrors.As(err, &netErr) && netErr.Timeout() {
+					return connectedAt, fmt.Errorf("websocket heartbeat missed: %w", err)
+				}
+				return connectedAt, fmt.Errorf("failed to read message: %w", hb.wrapError(err))
 			}
 
+			hb.dataReceived()
+
 			log.Printf("Received WebSocket data: %s", string(message))
 
-			// Парсим внешний JSON
-			var wsEvent struct {
-				Text string `json:"text"`
-			}
-			if err := json.Unmarshal(message, &wsEvent); err != nil {
-				log.Printf("Failed to unmarshal outer event: %v", err)
-				continue
+			if c.recorder != nil {
+				if err := c.recorder.Record(message); err != nil {
+					log.Printf("Failed to record frame: %v", err)
+				}
 			}
 
-			// Парсим внутренний JSON (данные события)
-			var event types.BaseEvent
-			if err := json.Unmarshal([]byte(wsEvent.Text), &event); err != nil {
-				log.Printf("Failed to unmarshal event: %v", err)
+			event, err := parseEvent(message)
+			if err != nil {
+				log.Printf("Failed to parse event: %v", err)
 				continue
 			}
 
-			// Игнорируем события "печатает"
-			if event.Event == types.EventTypeIsTyping {
-				continue
-			}
+			c.updatePresence(event)
+			handle(event)
 
-			// Обрабатываем новые сообщения
-			msg := c.processEvent(&event)
-			if msg != nil {
-				messageChan <- msg
-			}
+			// Подписчик мог долго принимать событие; это время не должно
+			// считаться молчанием сервера
+			hb.dataReceived()
 		}
 	}
 }
 
-func (c *Client) processEvent(event *types.BaseEvent) *types.Message {
+// parseEvent разбирает кадр WebSocket в типизированное событие
+func parseEvent(message []byte) (types.Event, error) {
+	// Парсим внешний JSON
+	var wsEvent struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(message, &wsEvent); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal outer event: %w", err)
+	}
+
+	// Парсим внутренний JSON (данные события)
+	var event types.BaseEvent
+	if err := json.Unmarshal([]byte(wsEvent.Text), &event); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal event: %w", err)
+	}
+
+	data := event.Data
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+
 	switch event.Event {
 	case types.EventTypeNewMessage:
-		return c.processNewMessageEvent(event)
+		text, _ := data["inboxMessage"].(string)
+		title, _ := data["title"].(string)
+		lastMessage, _ := data["lastMessage"].(map[string]interface{})
+		return &types.NewMessageEvent{
+			FromID:      intField(data, "from"),
+			ToUserID:    intField(data, "to_user_id"),
+			InboxID:     intField(data, "inbox_id"),
+			Text:        text,
+			Title:       title,
+			LastMessage: lastMessage,
+		}, nil
+
 	case types.EventTypeNotify:
-		return c.processNotifyEvent(event)
+		notify := &types.NotifyEvent{Data: data}
+		if newMsg, ok := data[types.NotifyNewMessage]; ok && newMsg != nil {
+			notify.NewMessage = true
+		}
+		if dialogData, ok := data["dialog_data"].([]interface{}); ok {
+			for _, item := range dialogData {
+				if info, ok := item.(map[string]interface{}); ok {
+					notify.DialogData = append(notify.DialogData, info)
+				}
+			}
+		}
+		return notify, nil
+
+	case types.EventTypeIsTyping:
+		return &types.TypingEvent{
+			FromID:   intField(data, "from"),
+			ToUserID: intField(data, "to", "to_user_id"),
+			Data:     data,
+		}, nil
+
+	case types.EventTypeMessageDelete:
+		return &types.MessageDeletedEvent{
+			MessageID: intField(data, "id", "message_id", "inbox_id"),
+			UserID:    intField(data, "user_id", "from"),
+			Data:      data,
+		}, nil
+
+	case types.EventTypeDialogUpdate:
+		return &types.DialogUpdatedEvent{
+			UserID: intField(data, "user_id", "from"),
+			Data:   data,
+		}, nil
+
 	case types.EventTypePopUpNotify:
-		return c.processPopUpNotifyEvent(event)
+		popUp := &types.PopUpNotifyEvent{Data: data}
+		if notify, ok := data["pop_up_notify"].(map[string]interface{}); ok {
+			popUp.ID = intField(notify, "id")
+			popUp.Type, _ = notify["type"].(string)
+			if notifyData, ok := notify["data"].(map[string]interface{}); ok {
+				popUp.Username, _ = notifyData["username"].(string)
+			}
+		}
+		return popUp, nil
+
+	case types.EventTypeRemovePopUpNotify:
+		return &types.RemovePopUpNotifyEvent{
+			ID:   intField(data, "id", "pop_up_notify_id"),
+			Data: data,
+		}, nil
+
 	default:
-		return nil
+		return &types.UnknownEvent{
+			Event: event.Event,
+			Raw:   json.RawMessage(wsEvent.Text),
+		}, nil
+	}
+}
+
+// intField возвращает первое найденное числовое поле из data
+func intField(data map[string]interface{}, keys ...string) int {
+	for _, key := range keys {
+		switch v := data[key].(type) {
+		case float64:
+			return int(v)
+		case string:
+			if n, err := strconv.Atoi(v); err == nil {
+				return n
+			}
+		}
 	}
+	return 0
 }
 
-func (c *Client) processNewMessageEvent(event *types.BaseEvent) *types.Message {
-	fromID, _ := event.Data["from"].(float64)
-	text, _ := event.Data["inboxMessage"].(string)
-	toUserID, _ := event.Data["to_user_id"].(float64)
-	inboxID, _ := event.Data["inbox_id"].(float64)
-	title, _ := event.Data["title"].(string)
-	lastMessage, _ := event.Data["lastMessage"].(map[string]interface{})
+// updatePresence обновляет кэш онлайн-статусов по событию
+func (c *Client) updatePresence(event types.Event) {
+	switch e := event.(type) {
+	case *types.NewMessageEvent:
+		c.presence.markOnline(e.FromID)
+	case *types.TypingEvent:
+		c.presence.markOnline(e.FromID)
+	}
+}
 
-	return types.NewMessage(
+// processEvent превращает событие во входящие сообщения, на которые оно указывает.
+// Собственные исходящие сообщения пропускаются.
+func (c *Client) processEvent(ctx context.Context, event types.Event) []*types.Message {
+	selfID, err := c.selfID(ctx)
+	if err != nil {
+		if c.replayFile == "" {
+			log.Printf("Failed to get own profile: %v", err)
+			return nil
+		}
+		// Воспроизведение должно работать без сети: обрабатываем события,
+		// не отфильтровывая свои сообщения
+		c.replayNoSelf.Do(func() {
+			log.Printf("Failed to get own profile during replay, own messages are not filtered (set Config.SelfID): %v", err)
+		})
+	}
+
+	var messages []*types.Message
+	switch e := event.(type) {
+	case *types.NewMessageEvent:
+		messages = []*types.Message{c.processNewMessageEvent(e)}
+	case *types.NotifyEvent:
+		messages = c.processNotifyEvent(ctx, e, selfID)
+	case *types.PopUpNotifyEvent:
+		if msg := c.processPopUpNotifyEvent(ctx, e, selfID); msg != nil {
+			messages = []*types.Message{msg}
+		}
+	}
+
+	inbound := messages[:0]
+	for _, msg := range messages {
+		if msg.FromID != 0 && msg.FromID != selfID {
+			inbound = append(inbound, msg)
+		}
+	}
+	return inbound
+}
+
+func (c *Client) processNewMessageEvent(event *types.NewMessageEvent) *types.Message {
+	msg := types.NewMessage(
 		c,
-		int(fromID),
-		text,
-		int(toUserID),
-		int(inboxID),
-		title,
-		lastMessage,
+		event.FromID,
+		event.Text,
+		event.ToUserID,
+		event.InboxID,
+		event.Title,
+		event.LastMessage,
 	)
-}
 
-func (c *Client) processNotifyEvent(event *types.BaseEvent) *types.Message {
-	// Проверяем наличие нового сообщения
-	if newMsg, ok := event.Data[types.NotifyNewMessage]; ok && newMsg != nil {
-		// Проверяем наличие данных диалога
-		if dialogData, ok := event.Data["dialog_data"]; !ok || dialogData == nil {
-			// Получаем последний диалог
-			ctx := context.Background()
-			dialogs, err := c.GetAllDialogs(ctx)
-			if err != nil || len(dialogs) == 0 {
-				log.Printf("Failed to get dialogs: %v", err)
-				return nil
-			}
+	msg.MessageID = intField(event.LastMessage, "message_id", "id")
+	if msg.MessageID == 0 {
+		msg.MessageID = event.InboxID
+	}
+	// Время сервера неизвестно — оставляем 0, а не подставляем локальное:
+	// по нему сдвигалась бы отметка досылки
+	msg.Time = intField(event.LastMessage, "time")
+	if files, ok := event.LastMessage["files"]; ok {
+		if err := decodeResponse(files, &msg.Files); err != nil {
+			log.Printf("Failed to parse message files: %v", err)
+		}
+	}
 
-			lastDialog := dialogs[0]
-			return types.NewMessage(
-				c,
-				lastDialog.UserID,
-				lastDialog.LastMessageText,
-				0,
-				0,
-				"",
-				nil,
-			)
-		} else {
-			// Есть данные диалога, получаем сообщение
-			dialogDataSlice, ok := event.Data["dialog_data"].([]interface{})
-			if !ok || len(dialogDataSlice) == 0 {
-				return nil
-			}
+	return msg
+}
 
-			dialogInfo, ok := dialogDataSlice[0].(map[string]interface{})
-			if !ok {
-				return nil
-			}
+// messageFromInbox создает сообщение бота из сообщения диалога
+func (c *Client) messageFromInbox(m types.InboxMessage) *types.Message {
+	msg := types.NewMessage(
+		c,
+		m.FromID,
+		m.Message,
+		m.ToID,
+		m.MessageID,
+		"",
+		nil,
+	)
+	msg.MessageID = m.MessageID
+	msg.Time = m.Time
+	msg.Files = m.Files
+	return msg
+}
+
+func (c *Client) processNotifyEvent(ctx context.Context, event *types.NotifyEvent, selfID int) []*types.Message {
+	// Проверяем наличие нового сообщения
+	if !event.NewMessage {
+		return nil
+	}
 
+	// Есть данные диалогов: берем последнее входящее сообщение в каждом
+	if len(event.DialogData) > 0 {
+		var messages []*types.Message
+		for _, dialogInfo := range event.DialogData {
 			login, _ := dialogInfo["login"].(string)
 			if login == "" {
-				return nil
+				continue
 			}
 
-			ctx := context.Background()
-			messages, err := c.GetDialogWithUser(ctx, login)
-			if err != nil || len(messages) == 0 {
+			msg, err := c.latestInboundMessage(ctx, login, selfID)
+			if err != nil {
 				log.Printf("Failed to get messages: %v", err)
-				return nil
+				continue
+			}
+			if msg != nil {
+				messages = append(messages, msg)
 			}
-
-			msg := messages[0]
-			return types.NewMessage(
-				c,
-				msg.FromID,
-				msg.Message,
-				msg.ToID,
-				msg.MessageID,
-				"",
-				nil,
-			)
 		}
+		return messages
 	}
 
-	return nil
-}
+	// Данных диалога нет: ищем самый свежий непрочитанный диалог,
+	// в котором последнее сообщение написал собеседник
+	dialogs, err := c.GetAllDialogs(ctx)
+	if err != nil {
+		log.Printf("Failed to get dialogs: %v", err)
+		return nil
+	}
 
-func (c *Client) processPopUpNotifyEvent(event *types.BaseEvent) *types.Message {
-	popUpNotify, ok := event.Data["pop_up_notify"].(map[string]interface{})
-	if !ok {
+	var latest *types.Dialog
+	for i := range dialogs {
+		dialog := &dialogs[i]
+		if dialog.LastMessage == nil || dialog.LastMessage.FromUserID == selfID {
+			continue
+		}
+		if !dialog.LastMessage.Unread && dialog.UnreadCount == 0 {
+			continue
+		}
+		if latest == nil || dialog.Time > latest.Time {
+			latest = dialog
+		}
+	}
+	if latest == nil {
 		return nil
 	}
 
-	data, ok := popUpNotify["data"].(map[string]interface{})
-	if !ok {
+	msg, err := c.latestInboundMessage(ctx, latest.Username, selfID)
+	if err != nil {
+		log.Printf("Failed to get messages: %v", err)
 		return nil
 	}
+	if msg == nil {
+		return nil
+	}
+	return []*types.Message{msg}
+}
 
-	username, _ := data["username"].(string)
-	if username == "" {
+func (c *Client) processPopUpNotifyEvent(ctx context.Context, event *types.PopUpNotifyEvent, selfID int) *types.Message {
+	if event.Username == "" {
 		return nil
 	}
 
-	ctx := context.Background()
-	messages, err := c.GetDialogWithUser(ctx, username)
-	if err != nil || len(messages) == 0 {
+	msg, err := c.latestInboundMessage(ctx, event.Username, selfID)
+	if err != nil {
 		log.Printf("Failed to get messages: %v", err)
 		return nil
 	}
+	return msg
+}
 
-	msg := messages[0]
-	return types.NewMessage(
-		c,
-		msg.FromID,
-		msg.Message,
-		msg.ToID,
-		msg.MessageID,
-		"",
-		nil,
-	)
+// latestInboundMessage возвращает самое новое сообщение от собеседника в диалоге.
+// Наши собственные сообщения не учитываются.
+func (c *Client) latestInboundMessage(ctx context.Context, username string, selfID int) (*types.Message, error) {
+	messages, err := c.GetDialogWithUser(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	var latest *types.InboxMessage
+	for i := range messages {
+		m := &messages[i]
+		if m.FromID == selfID {
+			continue
+		}
+		if latest == nil || m.Time > latest.Time || (m.Time == latest.Time && m.MessageID > latest.MessageID) {
+			latest = m
+		}
+	}
+
+	if latest == nil {
+		return nil, nil
+	}
+	return c.messageFromInbox(*latest), nil
 }
@@ -0,0 +1,165 @@
+package kwork
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/rtexty/gokwork/pkg/kwork/types"
+)
+
+const (
+	defaultHandlerWorkers = 10
+	defaultDrainTimeout   = 30 * time.Second
+)
+
+// handlerPool выполняет обработку сообщений на ограниченном числе воркеров.
+// Сообщения одного отправителя обрабатываются строго по очереди,
+// сообщения разных отправителей — параллельно.
+type handlerPool struct {
+	handle       func(ctx context.Context, msg *types.Message)
+	drainTimeout time.Duration
+
+	// ctx передается обработчикам и отменяется, только если они не успели
+	// завершиться за drainTimeout после остановки бота
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	slots chan struct{}
+	stop  chan struct{}
+	wg    sync.WaitGroup
+
+	mu      sync.Mutex
+	queues  map[int][]*types.Message
+	stopped bool
+	dropped int
+}
+
+func newHandlerPool(parent context.Context, workers int, drainTimeout time.Duration, handle func(context.Context, *types.Message)) *handlerPool {
+	if workers <= 0 {
+		workers = defaultHandlerWorkers
+	}
+	if drainTimeout <= 0 {
+		drainTimeout = defaultDrainTimeout
+	}
+
+	ctx, cancel := context.WithCancel(context.WithoutCancel(parent))
+	return &handlerPool{
+		handle:       handle,
+		drainTimeout: drainTimeout,
+		ctx:          ctx,
+		cancel:       cancel,
+		slots:        make(chan struct{}, workers),
+		stop:         make(chan struct{}),
+		queues:       make(map[int][]*types.Message),
+	}
+}
+
+// submit ставит сообщение в очередь отправителя
+func (p *handlerPool) submit(msg *types.Message) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.stopped {
+		return
+	}
+
+	queue := p.queues[msg.FromID]
+	p.queues[msg.FromID] = append(queue, msg)
+	if len(queue) > 0 {
+		// Очередь отправителя уже обрабатывается
+		return
+	}
+
+	p.wg.Add(1)
+	go p.run(msg.FromID)
+}
+
+// run обрабатывает очередь одного отправителя, занимая воркер на время
+// обработки каждого сообщения
+func (p *handlerPool) run(fromID int) {
+	defer p.wg.Done()
+
+	for {
+		select {
+		case p.slots <- struct{}{}:
+		case <-p.stop:
+			p.discard(fromID)
+			return
+		}
+
+		p.mu.Lock()
+		if p.stopped {
+			p.discardLocked(fromID)
+			p.mu.Unlock()
+			<-p.slots
+			return
+		}
+		msg := p.queues[fromID][0]
+		p.mu.Unlock()
+
+		p.handle(p.ctx, msg)
+		<-p.slots
+
+		p.mu.Lock()
+		queue := p.queues[fromID][1:]
+		if len(queue) == 0 {
+			delete(p.queues, fromID)
+			p.mu.Unlock()
+			return
+		}
+		p.queues[fromID] = queue
+		p.mu.Unlock()
+	}
+}
+
+// discard отбрасывает необработанные сообщения отправителя
+func (p *handlerPool) discard(fromID int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.discardLocked(fromID)
+}
+
+func (p *handlerPool) discardLocked(fromID int) {
+	p.dropped += len(p.queues[fromID])
+	delete(p.queues, fromID)
+}
+
+// wait дожидается обработки всех сообщений в очередях
+func (p *handlerPool) wait() {
+	p.wg.Wait()
+	p.cancel()
+}
+
+// drain перестает принимать сообщения, отбрасывает еще не начатые
+// и ждет завершения выполняющихся обработчиков. Если они не успели
+// за drainTimeout, их контекст отменяется.
+func (p *handlerPool) drain() {
+	p.mu.Lock()
+	p.stopped = true
+	p.mu.Unlock()
+	close(p.stop)
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(p.drainTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+	case <-timer.C:
+		log.Printf("Handlers did not finish in %s, cancelling", p.drainTimeout)
+		p.cancel()
+		<-done
+	}
+	p.cancel()
+
+	if p.dropped > 0 {
+		log.Printf("Bot stopped, %d queued messages were not handled", p.dropped)
+	}
+}
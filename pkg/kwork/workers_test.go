@@ -0,0 +1,149 @@
+package kwork
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rtexty/gokwork/pkg/kwork/types"
+)
+
+func TestHandlerPoolOrderPerSender(t *testing.T) {
+	var (
+		mu  sync.Mutex
+		got = make(map[int][]int)
+	)
+	pool := newHandlerPool(context.Background(), 4, time.Second, func(ctx context.Context, msg *types.Message) {
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		got[msg.FromID] = append(got[msg.FromID], msg.MessageID)
+		mu.Unlock()
+	})
+
+	const senders, perSender = 5, 20
+	for i := 0; i < perSender; i++ {
+		for from := 1; from <= senders; from++ {
+			pool.submit(&types.Message{FromID: from, MessageID: i})
+		}
+	}
+	pool.wait()
+
+	for from := 1; from <= senders; from++ {
+		ids := got[from]
+		if len(ids) != perSender {
+			t.Fatalf("sender %d: handled %d messages, want %d", from, len(ids), perSender)
+		}
+		for i, id := range ids {
+			if id != i {
+				t.Fatalf("sender %d: handled in order %v", from, ids)
+			}
+		}
+	}
+}
+
+func TestHandlerPoolLimitsWorkers(t *testing.T) {
+	const workers = 3
+
+	var (
+		mu      sync.Mutex
+		running int
+		peak    int
+	)
+	pool := newHandlerPool(context.Background(), workers, time.Second, func(ctx context.Context, msg *types.Message) {
+		mu.Lock()
+		running++
+		peak = max(peak, running)
+		mu.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+	})
+
+	for from := 1; from <= 20; from++ {
+		pool.submit(&types.Message{FromID: from})
+	}
+	pool.wait()
+
+	if peak > workers {
+		t.Errorf("%d handlers ran at once, limit %d", peak, workers)
+	}
+	if peak < 2 {
+		t.Errorf("handlers of different senders did not run in parallel (peak %d)", peak)
+	}
+}
+
+func TestHandlerPoolDrain(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	var (
+		mu      sync.Mutex
+		handled []int
+	)
+	pool := newHandlerPool(context.Background(), 1, time.Second, func(ctx context.Context, msg *types.Message) {
+		if msg.MessageID == 1 {
+			close(started)
+			<-release
+		}
+		mu.Lock()
+		handled = append(handled, msg.MessageID)
+		mu.Unlock()
+	})
+
+	pool.submit(&types.Message{FromID: 1, MessageID: 1})
+	<-started
+	pool.submit(&types.Message{FromID: 1, MessageID: 2})
+	pool.submit(&types.Message{FromID: 2, MessageID: 3})
+
+	done := make(chan struct{})
+	go func() {
+		pool.drain()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("drain returned before the running handler finished")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	<-done
+
+	pool.submit(&types.Message{FromID: 3, MessageID: 4})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !equalIDs(handled, []int{1}) {
+		t.Errorf("handled %v, want only the running message [1]", handled)
+	}
+	if pool.dropped != 2 {
+		t.Errorf("dropped = %d, want 2", pool.dropped)
+	}
+}
+
+func TestHandlerPoolDrainTimeout(t *testing.T) {
+	cancelled := make(chan struct{})
+	pool := newHandlerPool(context.Background(), 1, 20*time.Millisecond, func(ctx context.Context, msg *types.Message) {
+		<-ctx.Done()
+		close(cancelled)
+	})
+
+	pool.submit(&types.Message{FromID: 1})
+	time.Sleep(5 * time.Millisecond)
+
+	start := time.Now()
+	pool.drain()
+
+	select {
+	case <-cancelled:
+	default:
+		t.Fatal("handler context was not cancelled after drainTimeout")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("drain took %s", elapsed)
+	}
+}